@@ -0,0 +1,643 @@
+package acstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type threadRecord struct {
+	id           string
+	topic        string
+	createdOn    time.Time
+	createdBy    string
+	lastMessage  time.Time
+	participants map[string]*chatParticipant
+	order        []string
+	messages     []*chatMessage
+	sequence     int
+	// receipts holds the latest read receipt of each participant.
+	receipts map[string]*readReceipt
+	typing   int
+
+	metadata  map[string]string
+	retention json.RawMessage
+}
+
+type readReceipt struct {
+	SenderCommunicationIdentifier json.RawMessage `json:"senderCommunicationIdentifier"`
+	ChatMessageID                 string          `json:"chatMessageId"`
+	ReadOn                        string          `json:"readOn"`
+}
+
+type chatParticipant struct {
+	CommunicationIdentifier json.RawMessage `json:"communicationIdentifier"`
+	DisplayName             string          `json:"displayName,omitempty"`
+	ShareHistoryTime        string          `json:"shareHistoryTime,omitempty"`
+}
+
+type chatMessageContent struct {
+	Message                          string          `json:"message,omitempty"`
+	Topic                            string          `json:"topic,omitempty"`
+	InitiatorCommunicationIdentifier json.RawMessage `json:"initiatorCommunicationIdentifier,omitempty"`
+}
+
+type chatMessage struct {
+	ID                            string             `json:"id"`
+	Type                          string             `json:"type"`
+	SequenceID                    string             `json:"sequenceId"`
+	Version                       string             `json:"version"`
+	Content                       chatMessageContent `json:"content"`
+	SenderDisplayName             string             `json:"senderDisplayName,omitempty"`
+	CreatedOn                     string             `json:"createdOn"`
+	EditedOn                      string             `json:"editedOn,omitempty"`
+	DeletedOn                     string             `json:"deletedOn,omitempty"`
+	SenderCommunicationIdentifier json.RawMessage    `json:"senderCommunicationIdentifier,omitempty"`
+	Metadata                      map[string]string  `json:"metadata,omitempty"`
+
+	created time.Time
+}
+
+// chatVersions are the chat API versions the fake serves.
+var chatVersions = versions("2021-09-07", "2024-03-07")
+
+func (s *Server) chatRoutes() {
+	s.handle(http.MethodPost, "/chat/threads", authBearer, chatVersions, s.createChatThread)
+	s.handle(http.MethodGet, "/chat/threads", authBearer, chatVersions, s.listChatThreads)
+	s.handle(http.MethodGet, "/chat/threads/{id}", authBearer, chatVersions, s.getChatThread)
+	s.handle(http.MethodPatch, "/chat/threads/{id}", authBearer, chatVersions, s.updateChatThread)
+	s.handle(http.MethodDelete, "/chat/threads/{id}", authBearer, chatVersions, s.deleteChatThread)
+	s.handle(http.MethodGet, "/chat/threads/{id}/participants", authBearer, chatVersions, s.listChatParticipants)
+	s.handle(http.MethodPost, "/chat/threads/{id}/participants/:add", authBearer, chatVersions, s.addChatParticipants)
+	s.handle(http.MethodPost, "/chat/threads/{id}/participants/:remove", authBearer, chatVersions, s.removeChatParticipant)
+	s.handle(http.MethodPost, "/chat/threads/{id}/messages", authBearer, chatVersions, s.sendChatMessage)
+	s.handle(http.MethodGet, "/chat/threads/{id}/messages", authBearer, chatVersions, s.listChatMessages)
+	s.handle(http.MethodGet, "/chat/threads/{id}/messages/{messageId}", authBearer, chatVersions, s.getChatMessage)
+	s.handle(http.MethodPatch, "/chat/threads/{id}/messages/{messageId}", authBearer, chatVersions, s.updateChatMessage)
+	s.handle(http.MethodDelete, "/chat/threads/{id}/messages/{messageId}", authBearer, chatVersions, s.deleteChatMessage)
+	s.handle(http.MethodPost, "/chat/threads/{id}/readReceipts", authBearer, chatVersions, s.sendReadReceipt)
+	s.handle(http.MethodGet, "/chat/threads/{id}/readReceipts", authBearer, chatVersions, s.listReadReceipts)
+	s.handle(http.MethodPost, "/chat/threads/{id}/typing", authBearer, chatVersions, s.sendTypingNotification)
+}
+
+// userIdentifier is the wire form of an ACS user.
+func userIdentifier(id string) json.RawMessage {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"rawId":             id,
+		"communicationUser": map[string]string{"id": id},
+	})
+	return raw
+}
+
+func (t *threadRecord) add(p chatParticipant) {
+	id := rawID(p.CommunicationIdentifier)
+	if _, ok := t.participants[id]; !ok {
+		t.order = append(t.order, id)
+	}
+	t.participants[id] = &p
+}
+
+func (t *threadRecord) remove(id string) bool {
+	if _, ok := t.participants[id]; !ok {
+		return false
+	}
+	delete(t.participants, id)
+	for i, existing := range t.order {
+		if existing == id {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (t *threadRecord) message(id string) *chatMessage {
+	for _, m := range t.messages {
+		if m.ID == id && m.DeletedOn == "" {
+			return m
+		}
+	}
+	return nil
+}
+
+func (t *threadRecord) post(m *chatMessage) {
+	t.sequence++
+	now := time.Now().UTC()
+	m.SequenceID = strconv.Itoa(t.sequence)
+	m.ID = strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10) + strconv.Itoa(t.sequence)
+	m.Version = m.ID
+	m.CreatedOn = formatTime(now)
+	m.created = now
+	t.lastMessage = now
+	t.messages = append(t.messages, m)
+}
+
+// thread looks up a thread the caller participates in. It writes the error
+// response and returns nil when there is none.
+func (s *Server) thread(w http.ResponseWriter, r *request) *threadRecord {
+	thread, ok := s.threads[r.params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "thread "+r.params[0]+" does not exist")
+		return nil
+	}
+	if _, ok := thread.participants[r.caller.id]; !ok {
+		writeError(w, http.StatusForbidden, "Forbidden", "caller is not a participant of the thread")
+		return nil
+	}
+	return thread
+}
+
+// page writes one page of items, linking to the next one when more remain.
+func (s *Server) page(w http.ResponseWriter, r *request, items []interface{}) {
+	query := r.URL.Query()
+	skip, _ := strconv.Atoi(query.Get("skip"))
+	size, _ := strconv.Atoi(query.Get("maxPageSize"))
+	if size <= 0 {
+		size = 200
+	}
+	if skip > len(items) {
+		skip = len(items)
+	}
+	end := skip + size
+	if end > len(items) {
+		end = len(items)
+	}
+	response := map[string]interface{}{"value": items[skip:end]}
+	if end < len(items) {
+		next := url.Values{
+			"api-version": {query.Get("api-version")},
+			"maxPageSize": {strconv.Itoa(size)},
+			"skip":        {strconv.Itoa(end)},
+		}
+		response["nextLink"] = s.srv.URL + r.URL.EscapedPath() + "?" + next.Encode()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func parseStartTime(w http.ResponseWriter, r *request) (time.Time, bool) {
+	value := r.URL.Query().Get("startTime")
+	if value == "" {
+		return time.Time{}, true
+	}
+	startTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", "startTime must be an RFC 3339 timestamp")
+		return time.Time{}, false
+	}
+	return startTime, true
+}
+
+func (s *Server) createChatThread(w http.ResponseWriter, r *request) {
+	var req struct {
+		Topic        string            `json:"topic"`
+		Participants []chatParticipant `json:"participants"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	if req.Topic == "" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "topic is required")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	thread := &threadRecord{
+		id:           "19:" + newUUID() + "@thread.v2",
+		topic:        req.Topic,
+		createdOn:    now,
+		createdBy:    r.caller.id,
+		lastMessage:  now,
+		participants: map[string]*chatParticipant{},
+		receipts:     map[string]*readReceipt{},
+	}
+	thread.add(chatParticipant{CommunicationIdentifier: userIdentifier(r.caller.id)})
+	invalid := []map[string]string{}
+	for _, p := range req.Participants {
+		id := rawID(p.CommunicationIdentifier)
+		if _, ok := s.identities[id]; !ok {
+			invalid = append(invalid, map[string]string{
+				"target":  id,
+				"code":    "NotFound",
+				"message": "identity does not exist",
+			})
+			continue
+		}
+		thread.add(p)
+	}
+	s.threads[thread.id] = thread
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"chatThread": map[string]interface{}{
+			"id":                               thread.id,
+			"topic":                            thread.topic,
+			"createdOn":                        formatTime(thread.createdOn),
+			"createdByCommunicationIdentifier": userIdentifier(thread.createdBy),
+		},
+		"invalidParticipants": invalid,
+	})
+}
+
+func (s *Server) listChatThreads(w http.ResponseWriter, r *request) {
+	startTime, ok := parseStartTime(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	threads := []*threadRecord{}
+	for _, thread := range s.threads {
+		if _, ok := thread.participants[r.caller.id]; ok && !thread.lastMessage.Before(startTime) {
+			threads = append(threads, thread)
+		}
+	}
+	// Most recently active threads come first, like the service.
+	sort.Slice(threads, func(i, j int) bool {
+		if threads[i].lastMessage.Equal(threads[j].lastMessage) {
+			return threads[i].id < threads[j].id
+		}
+		return threads[i].lastMessage.After(threads[j].lastMessage)
+	})
+	items := []interface{}{}
+	for _, thread := range threads {
+		items = append(items, map[string]string{
+			"id":                    thread.id,
+			"topic":                 thread.topic,
+			"lastMessageReceivedOn": formatTime(thread.lastMessage),
+		})
+	}
+	s.page(w, r, items)
+}
+
+func (s *Server) getChatThread(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	properties := map[string]interface{}{
+		"id":                               thread.id,
+		"topic":                            thread.topic,
+		"createdOn":                        formatTime(thread.createdOn),
+		"createdByCommunicationIdentifier": userIdentifier(thread.createdBy),
+	}
+	if thread.metadata != nil {
+		properties["metadata"] = thread.metadata
+	}
+	if thread.retention != nil {
+		properties["retentionPolicy"] = thread.retention
+	}
+	writeJSON(w, http.StatusOK, properties)
+}
+
+func (s *Server) updateChatThread(w http.ResponseWriter, r *request) {
+	if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+		writeError(w, http.StatusUnsupportedMediaType, "UnsupportedMediaType", "updates must be sent as application/merge-patch+json")
+		return
+	}
+	var patch map[string]json.RawMessage
+	if err := r.decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	var topic string
+	if raw, ok := patch["topic"]; ok {
+		if err := json.Unmarshal(raw, &topic); err != nil || topic == "" {
+			writeError(w, http.StatusBadRequest, "BadRequest", "topic must be a non-empty string")
+			return
+		}
+	}
+	var metadata map[string]string
+	if raw, ok := patch["metadata"]; ok {
+		if err := json.Unmarshal(raw, &metadata); err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "metadata must be an object of strings")
+			return
+		}
+	}
+	var retention json.RawMessage
+	if raw, ok := patch["retentionPolicy"]; ok && string(raw) != "null" {
+		var policy struct {
+			Kind                  string `json:"kind"`
+			DeleteThreadAfterDays int    `json:"deleteThreadAfterDays"`
+		}
+		json.Unmarshal(raw, &policy)
+		switch {
+		case policy.Kind == "none":
+		case policy.Kind == "threadCreationDate" && policy.DeleteThreadAfterDays >= 1 && policy.DeleteThreadAfterDays <= 90:
+		default:
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid retention policy")
+			return
+		}
+		retention = raw
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	if topic != "" && topic != thread.topic {
+		thread.topic = topic
+		thread.post(&chatMessage{
+			Type: "topicUpdated",
+			Content: chatMessageContent{
+				Topic:                            topic,
+				InitiatorCommunicationIdentifier: userIdentifier(r.caller.id),
+			},
+		})
+	}
+	if _, ok := patch["metadata"]; ok {
+		thread.metadata = metadata
+	}
+	if _, ok := patch["retentionPolicy"]; ok {
+		thread.retention = retention
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteChatThread(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if thread := s.thread(w, r); thread != nil {
+		delete(s.threads, thread.id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) listChatParticipants(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	items := []interface{}{}
+	for _, id := range thread.order {
+		items = append(items, thread.participants[id])
+	}
+	s.page(w, r, items)
+}
+
+func (s *Server) addChatParticipants(w http.ResponseWriter, r *request) {
+	var req struct {
+		Participants []chatParticipant `json:"participants"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	invalid := []map[string]string{}
+	for _, p := range req.Participants {
+		id := rawID(p.CommunicationIdentifier)
+		if _, ok := s.identities[id]; !ok {
+			invalid = append(invalid, map[string]string{
+				"target":  id,
+				"code":    "NotFound",
+				"message": "identity does not exist",
+			})
+			continue
+		}
+		thread.add(p)
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"invalidParticipants": invalid,
+	})
+}
+
+func (s *Server) removeChatParticipant(w http.ResponseWriter, r *request) {
+	var identifier json.RawMessage
+	if err := r.decode(&identifier); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	if !thread.remove(rawID(identifier)) {
+		writeError(w, http.StatusNotFound, "NotFound", "participant is not in the thread")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) sendChatMessage(w http.ResponseWriter, r *request) {
+	var req struct {
+		Content           string            `json:"content"`
+		SenderDisplayName string            `json:"senderDisplayName"`
+		Type              string            `json:"type"`
+		Metadata          map[string]string `json:"metadata"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	switch req.Type {
+	case "":
+		req.Type = "text"
+	case "text", "html":
+	default:
+		writeError(w, http.StatusBadRequest, "BadRequest", "unsupported message type "+req.Type)
+		return
+	}
+	if req.Content == "" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "content is required")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	message := &chatMessage{
+		Type:                          req.Type,
+		Content:                       chatMessageContent{Message: req.Content},
+		SenderDisplayName:             req.SenderDisplayName,
+		SenderCommunicationIdentifier: userIdentifier(r.caller.id),
+		Metadata:                      req.Metadata,
+	}
+	thread.post(message)
+	writeJSON(w, http.StatusCreated, map[string]string{"id": message.ID})
+}
+
+func (s *Server) listChatMessages(w http.ResponseWriter, r *request) {
+	startTime, ok := parseStartTime(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	// Newest messages come first, like the service.
+	items := []interface{}{}
+	for i := len(thread.messages) - 1; i >= 0; i-- {
+		m := thread.messages[i]
+		if m.DeletedOn == "" && !m.created.Before(startTime) {
+			items = append(items, m)
+		}
+	}
+	s.page(w, r, items)
+}
+
+func (s *Server) getChatMessage(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	message := thread.message(r.params[1])
+	if message == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "message "+r.params[1]+" does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+func (s *Server) updateChatMessage(w http.ResponseWriter, r *request) {
+	if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+		writeError(w, http.StatusUnsupportedMediaType, "UnsupportedMediaType", "updates must be sent as application/merge-patch+json")
+		return
+	}
+	var req struct {
+		Content  *string           `json:"content"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	message := thread.message(r.params[1])
+	if message == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "message "+r.params[1]+" does not exist")
+		return
+	}
+	if rawID(message.SenderCommunicationIdentifier) != r.caller.id {
+		writeError(w, http.StatusForbidden, "Forbidden", "only the sender can edit a message")
+		return
+	}
+	if req.Content != nil {
+		message.Content.Message = *req.Content
+	}
+	if req.Metadata != nil {
+		if message.Metadata == nil {
+			message.Metadata = map[string]string{}
+		}
+		for k, v := range req.Metadata {
+			message.Metadata[k] = v
+		}
+	}
+	message.EditedOn = formatTime(time.Now())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteChatMessage(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	message := thread.message(r.params[1])
+	if message == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "message "+r.params[1]+" does not exist")
+		return
+	}
+	message.DeletedOn = formatTime(time.Now())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) sendReadReceipt(w http.ResponseWriter, r *request) {
+	var req struct {
+		ChatMessageID string `json:"chatMessageId"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	if req.ChatMessageID == "" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "chatMessageId is required")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	if thread.message(req.ChatMessageID) == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "message "+req.ChatMessageID+" does not exist")
+		return
+	}
+	thread.receipts[r.caller.id] = &readReceipt{
+		SenderCommunicationIdentifier: userIdentifier(r.caller.id),
+		ChatMessageID:                 req.ChatMessageID,
+		ReadOn:                        formatTime(time.Now()),
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listReadReceipts(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := s.thread(w, r)
+	if thread == nil {
+		return
+	}
+	items := []interface{}{}
+	for _, id := range thread.order {
+		if receipt, ok := thread.receipts[id]; ok {
+			items = append(items, receipt)
+		}
+	}
+	s.page(w, r, items)
+}
+
+func (s *Server) sendTypingNotification(w http.ResponseWriter, r *request) {
+	var req struct {
+		SenderDisplayName string `json:"senderDisplayName"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if thread := s.thread(w, r); thread != nil {
+		thread.typing++
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// TypingNotifications returns how many typing notifications were sent to
+// a thread.
+func (s *Server) TypingNotifications(threadID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if thread, ok := s.threads[threadID]; ok {
+		return thread.typing
+	}
+	return 0
+}
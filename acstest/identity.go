@@ -0,0 +1,316 @@
+package acstest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type identityRecord struct {
+	id                string
+	customID          string
+	lastTokenIssuedAt time.Time
+}
+
+func (i *identityRecord) model() map[string]string {
+	model := map[string]string{"id": i.id}
+	if i.customID != "" {
+		model["customId"] = i.customID
+	}
+	if !i.lastTokenIssuedAt.IsZero() {
+		model["lastTokenIssuedAt"] = formatTime(i.lastTokenIssuedAt)
+	}
+	return model
+}
+
+type tokenRecord struct {
+	identityID string
+	scopes     []string
+	expiresOn  time.Time
+}
+
+type accessToken struct {
+	Token     string `json:"token"`
+	ExpiresOn string `json:"expiresOn"`
+}
+
+// customIDVersion is the identity API version that publishes custom ids.
+const customIDVersion = "2025-03-02-preview"
+
+// identityVersions are the identity API versions the fake serves.
+var identityVersions = versions("2022-10-01", "2023-10-01", customIDVersion)
+
+func (s *Server) identityRoutes() {
+	s.handle(http.MethodPost, "/identities", authKey, identityVersions, s.createIdentity)
+	s.handle(http.MethodGet, "/identities/{id}", authKey, identityVersions, s.getIdentity)
+	s.handle(http.MethodDelete, "/identities/{id}", authKey, identityVersions, s.deleteIdentity)
+	s.handle(http.MethodPost, "/identities/{id}/:issueAccessToken", authKey, identityVersions, s.issueAccessToken)
+	s.handle(http.MethodPost, "/identities/{id}/:revokeAccessTokens", authKey, identityVersions, s.revokeAccessTokens)
+	s.handle(http.MethodPost, "/teamsUser/:exchangeAccessToken", authKey, identityVersions, s.exchangeTeamsToken)
+}
+
+// CreateIdentity adds an identity directly, bypassing the REST API, and
+// returns its id.
+func (s *Server) CreateIdentity() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createIdentityLocked().id
+}
+
+// IssueToken issues a token for an existing identity, bypassing the REST
+// API.
+func (s *Server) IssueToken(identityID string, scopes []string, validFor time.Duration) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueTokenLocked(identityID, scopes, validFor).Token
+}
+
+func (s *Server) createIdentityLocked() *identityRecord {
+	id := &identityRecord{id: "8:acs:" + s.resource + "_" + newUUID()}
+	s.identities[id.id] = id
+	return id
+}
+
+func (s *Server) issueTokenLocked(
+	identityID string,
+	scopes []string,
+	validFor time.Duration,
+) accessToken {
+	expiresOn := time.Now().Add(validFor).Truncate(time.Second)
+	claims, _ := json.Marshal(map[string]interface{}{
+		"skypeid": strings.TrimPrefix(identityID, "8:"),
+		"scp":     strings.Join(scopes, " "),
+		"exp":     expiresOn.Unix(),
+		"jti":     newUUID(),
+	})
+	token := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." +
+		base64.RawURLEncoding.EncodeToString(claims) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(newUUID()))
+	if id, ok := s.identities[identityID]; ok {
+		id.lastTokenIssuedAt = time.Now()
+	}
+	s.tokens[token] = &tokenRecord{
+		identityID: identityID,
+		scopes:     scopes,
+		expiresOn:  expiresOn,
+	}
+	return accessToken{token, formatTime(expiresOn)}
+}
+
+type tokenOptions struct {
+	CustomID              string   `json:"customId"`
+	Scopes                []string `json:"scopes"`
+	CreateTokenWithScopes []string `json:"createTokenWithScopes"`
+	ExpiresInMinutes      *int     `json:"expiresInMinutes"`
+}
+
+// validFor validates the requested token lifetime, defaulting to 24 hours.
+func (o *tokenOptions) validFor() (time.Duration, bool) {
+	if o.ExpiresInMinutes == nil {
+		return 24 * time.Hour, true
+	}
+	if *o.ExpiresInMinutes < 60 || *o.ExpiresInMinutes > 1440 {
+		return 0, false
+	}
+	return time.Duration(*o.ExpiresInMinutes) * time.Minute, true
+}
+
+func (s *Server) createIdentity(w http.ResponseWriter, r *request) {
+	var opts tokenOptions
+	if err := r.decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	for _, scope := range opts.CreateTokenWithScopes {
+		if !knownScope(scope) {
+			writeError(w, http.StatusBadRequest, "ValidationError", "unknown scope "+scope)
+			return
+		}
+	}
+	validFor, ok := opts.validFor()
+	if !ok {
+		writeError(w, http.StatusBadRequest, "ValidationError", "expiresInMinutes must be between 60 and 1440")
+		return
+	}
+	if opts.CustomID != "" && r.URL.Query().Get("api-version") != customIDVersion {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", "customId requires api-version "+customIDVersion)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var id *identityRecord
+	if opts.CustomID != "" {
+		for _, existing := range s.identities {
+			if existing.customID == opts.CustomID {
+				id = existing
+				break
+			}
+		}
+	}
+	if id == nil {
+		id = s.createIdentityLocked()
+		id.customID = opts.CustomID
+	}
+	response := map[string]interface{}{}
+	if len(opts.CreateTokenWithScopes) > 0 {
+		response["accessToken"] = s.issueTokenLocked(id.id, opts.CreateTokenWithScopes, validFor)
+	}
+	response["identity"] = id.model()
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (s *Server) getIdentity(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.identities[r.params[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "IdentityNotFound", "identity "+r.params[0]+" does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, id.model())
+}
+
+func (s *Server) deleteIdentity(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.params[0]
+	if _, ok := s.identities[id]; !ok {
+		writeError(w, http.StatusNotFound, "IdentityNotFound", "identity "+id+" does not exist")
+		return
+	}
+	delete(s.identities, id)
+	s.revokeLocked(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) issueAccessToken(w http.ResponseWriter, r *request) {
+	var opts tokenOptions
+	if err := r.decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	if len(opts.Scopes) == 0 {
+		writeError(w, http.StatusBadRequest, "ValidationError", "scopes cannot be empty")
+		return
+	}
+	for _, scope := range opts.Scopes {
+		if !knownScope(scope) {
+			writeError(w, http.StatusBadRequest, "ValidationError", "unknown scope "+scope)
+			return
+		}
+	}
+	validFor, ok := opts.validFor()
+	if !ok {
+		writeError(w, http.StatusBadRequest, "ValidationError", "expiresInMinutes must be between 60 and 1440")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.params[0]
+	if _, ok := s.identities[id]; !ok {
+		writeError(w, http.StatusNotFound, "IdentityNotFound", "identity "+id+" does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.issueTokenLocked(id, opts.Scopes, validFor))
+}
+
+func (s *Server) revokeAccessTokens(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.params[0]
+	if _, ok := s.identities[id]; !ok {
+		writeError(w, http.StatusNotFound, "IdentityNotFound", "identity "+id+" does not exist")
+		return
+	}
+	s.revokeLocked(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) revokeLocked(identityID string) {
+	for token, record := range s.tokens {
+		if record.identityID == identityID {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+func knownScope(scope string) bool {
+	switch scope {
+	case "chat", "voip", "chat.join", "chat.join.limited", "voip.join":
+		return true
+	}
+	return false
+}
+
+// TeamsToken returns an Entra ID access token of a Teams user, as the
+// Microsoft identity platform would issue it to appID, for exchange with
+// GetTokenForTeamsUser.
+func (s *Server) TeamsToken(appID string, userObjectID string, validFor time.Duration) string {
+	claims, _ := json.Marshal(map[string]interface{}{
+		"aud":   "https://auth.msft.communication.azure.com",
+		"appid": appID,
+		"oid":   userObjectID,
+		"scp":   "Teams.ManageCalls Teams.ManageChats",
+		"exp":   time.Now().Add(validFor).Unix(),
+	})
+	return "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." +
+		base64.RawURLEncoding.EncodeToString(claims) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(newUUID()))
+}
+
+func (s *Server) exchangeTeamsToken(w http.ResponseWriter, r *request) {
+	var req struct {
+		Token  string `json:"token"`
+		AppID  string `json:"appId"`
+		UserID string `json:"userId"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	if req.Token == "" || req.AppID == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "ValidationError", "token, appId and userId are required")
+		return
+	}
+	var claims struct {
+		AppID string `json:"appid"`
+		OID   string `json:"oid"`
+		Exp   int64  `json:"exp"`
+	}
+	parts := strings.Split(req.Token, ".")
+	if len(parts) != 3 {
+		writeError(w, http.StatusUnauthorized, "InvalidAccessToken", "token is not a JWT")
+		return
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		writeError(w, http.StatusUnauthorized, "InvalidAccessToken", "token is not a JWT")
+		return
+	}
+	expiresOn := time.Unix(claims.Exp, 0)
+	if !time.Now().Before(expiresOn) {
+		writeError(w, http.StatusUnauthorized, "InvalidAccessToken", "token expired")
+		return
+	}
+	if claims.AppID != req.AppID {
+		writeError(w, http.StatusBadRequest, "AppIdMismatch", "appId does not match the token")
+		return
+	}
+	if claims.OID != req.UserID {
+		writeError(w, http.StatusBadRequest, "UserIdMismatch", "userId does not match the token")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := "8:orgid:" + req.UserID
+	if _, ok := s.identities[id]; !ok {
+		s.identities[id] = &identityRecord{id: id}
+	}
+	validFor := time.Until(expiresOn)
+	if validFor > 24*time.Hour {
+		validFor = 24 * time.Hour
+	}
+	writeJSON(w, http.StatusOK, s.issueTokenLocked(id, []string{"chat", "voip"}, validFor))
+}
@@ -0,0 +1,433 @@
+package acstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type roomRecord struct {
+	id             string
+	created        time.Time
+	validFrom      time.Time
+	validUntil     time.Time
+	roomJoinPolicy string
+	pstnDialOut    bool
+	participants   map[string]roomParticipant
+	order          []string
+}
+
+type roomParticipant struct {
+	CommunicationIdentifier json.RawMessage `json:"communicationIdentifier"`
+	Role                    string          `json:"role,omitempty"`
+}
+
+// roomRequest is the body of a create or update. Participants is a list
+// in the preview API and a map of raw id to role in the GA API.
+type roomRequest struct {
+	ValidFrom          *time.Time      `json:"validFrom"`
+	ValidUntil         *time.Time      `json:"validUntil"`
+	RoomJoinPolicy     string          `json:"roomJoinPolicy"`
+	PstnDialOutEnabled *bool           `json:"pstnDialOutEnabled"`
+	Participants       json.RawMessage `json:"participants"`
+}
+
+type participantsRequest struct {
+	Participants []roomParticipant `json:"participants"`
+}
+
+type participantsPatch struct {
+	Participants map[string]*struct {
+		Role string `json:"role"`
+	} `json:"participants"`
+}
+
+var (
+	// gaRoomsVersions are the GA rooms API versions the fake serves.
+	gaRoomsVersions = versions("2023-06-14", "2024-04-15")
+	// roomsVersions adds the preview API, which cannot list rooms.
+	roomsVersions = versions("2022-02-01", "2023-06-14", "2024-04-15")
+)
+
+// gaRooms reports whether r uses a GA rooms API version, the first of
+// which was 2023-06-14.
+func gaRooms(r *request) bool {
+	return r.URL.Query().Get("api-version") >= "2023-06-14"
+}
+
+// decodeParticipants reads either participants shape. A nil entry in the
+// GA map, sent as null, is returned with an empty Role and remove set.
+func decodeParticipants(raw json.RawMessage) (participants []roomParticipant, remove []string, err error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil, nil
+	}
+	if raw[0] == '[' {
+		err = json.Unmarshal(raw, &participants)
+		return participants, nil, err
+	}
+	var patch participantsPatch
+	if err := json.Unmarshal(raw, &patch.Participants); err != nil {
+		return nil, nil, err
+	}
+	ids := []string{}
+	for id := range patch.Participants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p := patch.Participants[id]
+		if p == nil {
+			remove = append(remove, id)
+			continue
+		}
+		identifier, _ := json.Marshal(map[string]string{"rawId": id})
+		participants = append(participants, roomParticipant{identifier, p.Role})
+	}
+	return participants, remove, nil
+}
+
+func (s *Server) roomsRoutes() {
+	s.handle(http.MethodPost, "/rooms", authKey, roomsVersions, s.createRoom)
+	s.handle(http.MethodGet, "/rooms", authKey, gaRoomsVersions, s.listRooms)
+	s.handle(http.MethodGet, "/rooms/{id}", authKey, roomsVersions, s.getRoom)
+	s.handle(http.MethodPatch, "/rooms/{id}", authKey, roomsVersions, s.updateRoom)
+	s.handle(http.MethodDelete, "/rooms/{id}", authKey, roomsVersions, s.deleteRoom)
+	s.handle(http.MethodGet, "/rooms/{id}/participants", authKey, roomsVersions, s.getRoomParticipants)
+	s.handle(http.MethodPatch, "/rooms/{id}/participants", authKey, roomsVersions, s.patchRoomParticipants)
+	s.handle(http.MethodPost, "/rooms/{id}/participants:add", authKey, roomsVersions, s.addRoomParticipants)
+	s.handle(http.MethodPost, "/rooms/{id}/participants:update", authKey, roomsVersions, s.updateRoomParticipants)
+	s.handle(http.MethodPost, "/rooms/{id}/participants:remove", authKey, roomsVersions, s.removeRoomParticipants)
+}
+
+// rawID extracts the raw id of a communication identifier, accepting both
+// the {"rawId"} and {"communicationUser":{"id"}} shapes.
+func rawID(identifier json.RawMessage) string {
+	var id struct {
+		RawID             string `json:"rawId"`
+		ID                string `json:"id"`
+		CommunicationUser *struct {
+			ID string `json:"id"`
+		} `json:"communicationUser"`
+	}
+	json.Unmarshal(identifier, &id)
+	switch {
+	case id.RawID != "":
+		return id.RawID
+	case id.CommunicationUser != nil:
+		return id.CommunicationUser.ID
+	}
+	return id.ID
+}
+
+func (r *roomRecord) upsert(p roomParticipant) {
+	id := rawID(p.CommunicationIdentifier)
+	if _, ok := r.participants[id]; !ok {
+		r.order = append(r.order, id)
+	}
+	if p.Role == "" {
+		p.Role = "Attendee"
+	}
+	r.participants[id] = p
+}
+
+func (r *roomRecord) remove(id string) {
+	delete(r.participants, id)
+	for i, existing := range r.order {
+		if existing == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (r *roomRecord) participantList() []roomParticipant {
+	list := []roomParticipant{}
+	for _, id := range r.order {
+		list = append(list, r.participants[id])
+	}
+	return list
+}
+
+func (r *roomRecord) model(ga bool) map[string]interface{} {
+	if ga {
+		return map[string]interface{}{
+			"id":                 r.id,
+			"createdAt":          formatTime(r.created),
+			"validFrom":          formatTime(r.validFrom),
+			"validUntil":         formatTime(r.validUntil),
+			"pstnDialOutEnabled": r.pstnDialOut,
+		}
+	}
+	return map[string]interface{}{
+		"id":              r.id,
+		"createdDateTime": formatTime(r.created),
+		"validFrom":       formatTime(r.validFrom),
+		"validUntil":      formatTime(r.validUntil),
+		"roomJoinPolicy":  r.roomJoinPolicy,
+		"participants":    r.participantList(),
+	}
+}
+
+func (s *Server) room(w http.ResponseWriter, id string) *roomRecord {
+	room, ok := s.rooms[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "room "+id+" does not exist")
+		return nil
+	}
+	return room
+}
+
+func validRoomWindow(w http.ResponseWriter, from time.Time, until time.Time) bool {
+	if !from.Before(until) {
+		writeError(w, http.StatusBadRequest, "BadRequest", "validFrom must be before validUntil")
+		return false
+	}
+	if until.Sub(from) > 180*24*time.Hour {
+		writeError(w, http.StatusBadRequest, "BadRequest", "rooms can be valid for at most 180 days")
+		return false
+	}
+	return true
+}
+
+func (s *Server) createRoom(w http.ResponseWriter, r *request) {
+	var req roomRequest
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	now := time.Now().UTC()
+	room := &roomRecord{
+		created:        now,
+		validFrom:      now,
+		validUntil:     now.Add(180 * 24 * time.Hour),
+		roomJoinPolicy: "InviteOnly",
+		participants:   map[string]roomParticipant{},
+	}
+	if req.ValidFrom != nil && !req.ValidFrom.IsZero() {
+		room.validFrom = *req.ValidFrom
+	}
+	if req.ValidUntil != nil && !req.ValidUntil.IsZero() {
+		room.validUntil = *req.ValidUntil
+	}
+	if req.RoomJoinPolicy != "" {
+		room.roomJoinPolicy = req.RoomJoinPolicy
+	}
+	if req.PstnDialOutEnabled != nil {
+		room.pstnDialOut = *req.PstnDialOutEnabled
+	}
+	if !validRoomWindow(w, room.validFrom, room.validUntil) {
+		return
+	}
+	participants, _, err := decodeParticipants(req.Participants)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	for _, p := range participants {
+		room.upsert(p)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room.id = newRoomID(s.nextID())
+	s.rooms[room.id] = room
+	writeJSON(w, http.StatusCreated, room.model(gaRooms(r)))
+}
+
+func newRoomID(n int) string {
+	return fmt.Sprintf("99%s%06d", time.Now().UTC().Format("20060102150405"), n)
+}
+
+func (s *Server) listRooms(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rooms := []*roomRecord{}
+	for _, room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].id < rooms[j].id
+	})
+	items := []interface{}{}
+	for _, room := range rooms {
+		items = append(items, room.model(gaRooms(r)))
+	}
+	s.page(w, r, items)
+}
+
+func (s *Server) getRoom(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room := s.room(w, r.params[0]); room != nil {
+		writeJSON(w, http.StatusOK, room.model(gaRooms(r)))
+	}
+}
+
+func (s *Server) updateRoom(w http.ResponseWriter, r *request) {
+	ga := gaRooms(r)
+	if ga && !mergePatch(w, r) {
+		return
+	}
+	var req roomRequest
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.room(w, r.params[0])
+	if room == nil {
+		return
+	}
+	from, until := room.validFrom, room.validUntil
+	if req.ValidFrom != nil && !req.ValidFrom.IsZero() {
+		from = *req.ValidFrom
+	}
+	if req.ValidUntil != nil && !req.ValidUntil.IsZero() {
+		until = *req.ValidUntil
+	}
+	if !validRoomWindow(w, from, until) {
+		return
+	}
+	participants, _, err := decodeParticipants(req.Participants)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	room.validFrom, room.validUntil = from, until
+	if req.RoomJoinPolicy != "" {
+		room.roomJoinPolicy = req.RoomJoinPolicy
+	}
+	if req.PstnDialOutEnabled != nil {
+		room.pstnDialOut = *req.PstnDialOutEnabled
+	}
+	for _, p := range participants {
+		room.upsert(p)
+	}
+	writeJSON(w, http.StatusOK, room.model(ga))
+}
+
+func (s *Server) deleteRoom(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room := s.room(w, r.params[0]); room != nil {
+		delete(s.rooms, room.id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) getRoomParticipants(w http.ResponseWriter, r *request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.room(w, r.params[0])
+	if room == nil {
+		return
+	}
+	if !gaRooms(r) {
+		writeJSON(w, http.StatusOK, participantsRequest{room.participantList()})
+		return
+	}
+	items := []interface{}{}
+	for _, id := range room.order {
+		items = append(items, map[string]string{
+			"rawId": id,
+			"role":  room.participants[id].Role,
+		})
+	}
+	s.page(w, r, items)
+}
+
+// patchRoomParticipants is the GA merge-patch that upserts participants
+// and removes those whose value is null.
+func (s *Server) patchRoomParticipants(w http.ResponseWriter, r *request) {
+	if !mergePatch(w, r) {
+		return
+	}
+	var req struct {
+		Participants json.RawMessage `json:"participants"`
+	}
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	participants, remove, err := decodeParticipants(req.Participants)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.room(w, r.params[0])
+	if room == nil {
+		return
+	}
+	for _, p := range participants {
+		room.upsert(p)
+	}
+	for _, id := range remove {
+		room.remove(id)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) changeRoomParticipants(
+	w http.ResponseWriter,
+	r *request,
+	change func(room *roomRecord, p roomParticipant) bool,
+) {
+	var req participantsRequest
+	if err := r.decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.room(w, r.params[0])
+	if room == nil {
+		return
+	}
+	for _, p := range req.Participants {
+		if !change(room, p) {
+			writeError(w, http.StatusBadRequest, "BadRequest", "participant "+rawID(p.CommunicationIdentifier)+" is not in the room")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, participantsRequest{room.participantList()})
+}
+
+func (s *Server) addRoomParticipants(w http.ResponseWriter, r *request) {
+	s.changeRoomParticipants(w, r, func(room *roomRecord, p roomParticipant) bool {
+		room.upsert(p)
+		return true
+	})
+}
+
+func (s *Server) updateRoomParticipants(w http.ResponseWriter, r *request) {
+	s.changeRoomParticipants(w, r, func(room *roomRecord, p roomParticipant) bool {
+		if _, ok := room.participants[rawID(p.CommunicationIdentifier)]; !ok {
+			return false
+		}
+		room.upsert(p)
+		return true
+	})
+}
+
+func (s *Server) removeRoomParticipants(w http.ResponseWriter, r *request) {
+	s.changeRoomParticipants(w, r, func(room *roomRecord, p roomParticipant) bool {
+		room.remove(rawID(p.CommunicationIdentifier))
+		return true
+	})
+}
+
+// Rooms returns the ids of every room, sorted.
+func (s *Server) Rooms() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []string{}
+	for id := range s.rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,429 @@
+// Package acstest provides an in-process fake of the Azure Communication
+// Services REST APIs used by this SDK, for tests that must run offline.
+//
+// The fake keeps identities, tokens, rooms and chat threads in memory. It
+// verifies HMAC-SHA256 signatures on access-key requests and checks the
+// bearer tokens it issued on chat requests, and rejects api-version values
+// an operation is not published in, so authentication and versioning bugs
+// surface the same way they would against the real service.
+package acstest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+// maxClockSkew is how far x-ms-date may be from the server clock.
+const maxClockSkew = 15 * time.Minute
+
+// Server is a fake ACS resource served over TLS on a local port.
+type Server struct {
+	srv      *httptest.Server
+	key      []byte
+	resource string
+	basePath string
+	routes   []route
+
+	mu         sync.Mutex
+	seq        int
+	identities map[string]*identityRecord
+	tokens     map[string]*tokenRecord
+	rooms      map[string]*roomRecord
+	threads    map[string]*threadRecord
+	replays    map[string]*recordedResponse
+}
+
+type authKind int
+
+const (
+	authKey authKind = iota
+	authBearer
+)
+
+type route struct {
+	method   string
+	pattern  []string
+	auth     authKind
+	versions apiVersions
+	handler  func(w http.ResponseWriter, r *request)
+}
+
+// apiVersions is the set of api-version values a route is published in.
+type apiVersions map[string]bool
+
+func versions(published ...string) apiVersions {
+	set := apiVersions{}
+	for _, v := range published {
+		set[v] = true
+	}
+	return set
+}
+
+// request is an authenticated request routed to a handler.
+type request struct {
+	*http.Request
+	params []string
+	// caller is the identity the bearer token was issued to.
+	caller *identityRecord
+	body   []byte
+}
+
+func (r *request) decode(out interface{}) error {
+	if len(r.body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.body, out)
+}
+
+// ServerOptions configure how the fake resource is served.
+type ServerOptions struct {
+	// PlainHTTP serves over http instead of https, like a local emulator.
+	PlainHTTP bool
+	// BasePath is a path prefix every resource path lives under, as when
+	// the resource sits behind a reverse proxy.
+	BasePath string
+}
+
+// NewServer starts a fake ACS resource. Callers must Close it.
+func NewServer() *Server {
+	return NewServerWithOptions(nil)
+}
+
+// NewServerWithOptions starts a fake ACS resource configured by opts.
+// Callers must Close it.
+func NewServerWithOptions(opts *ServerOptions) *Server {
+	if opts == nil {
+		opts = &ServerOptions{}
+	}
+	s := &Server{
+		key:        make([]byte, 32),
+		resource:   newUUID(),
+		identities: map[string]*identityRecord{},
+		tokens:     map[string]*tokenRecord{},
+		rooms:      map[string]*roomRecord{},
+		threads:    map[string]*threadRecord{},
+		replays:    map[string]*recordedResponse{},
+	}
+	rand.Read(s.key)
+	if base := strings.Trim(opts.BasePath, "/"); base != "" {
+		s.basePath = "/" + base
+	}
+	s.identityRoutes()
+	s.roomsRoutes()
+	s.chatRoutes()
+	if opts.PlainHTTP {
+		s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	} else {
+		s.srv = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	}
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Host is the host and port of the server. It can be passed to the SDK
+// constructors when the server uses https and has no base path.
+func (s *Server) Host() string {
+	return s.srv.Listener.Addr().String()
+}
+
+// Endpoint is the full URL of the fake resource, including the scheme and
+// base path.
+func (s *Server) Endpoint() string {
+	return s.srv.URL + s.basePath + "/"
+}
+
+// Key is the base64 encoded access key accepted by the server.
+func (s *Server) Key() string {
+	return base64.StdEncoding.EncodeToString(s.key)
+}
+
+// ConnectionString returns an ACS connection string for the server.
+func (s *Server) ConnectionString() string {
+	return "endpoint=" + s.Endpoint() + ";accesskey=" + s.Key()
+}
+
+// Client returns an http.Client that trusts the server certificate.
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// ClientOptions returns the options SDK clients need to reach the server.
+func (s *Server) ClientOptions() []client.Option {
+	return []client.Option{client.WithHTTPClient(s.Client())}
+}
+
+func (s *Server) handle(
+	method string,
+	path string,
+	auth authKind,
+	versions apiVersions,
+	handler func(w http.ResponseWriter, r *request),
+) {
+	s.routes = append(s.routes, route{
+		method:   method,
+		pattern:  strings.Split(strings.Trim(path, "/"), "/"),
+		auth:     auth,
+		versions: versions,
+		handler:  handler,
+	})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	path := r.URL.EscapedPath()
+	if s.basePath != "" {
+		if !strings.HasPrefix(path, s.basePath+"/") {
+			writeError(w, http.StatusNotFound, "NotFound", "no route for "+r.URL.Path)
+			return
+		}
+		path = strings.TrimPrefix(path, s.basePath)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	pathMatched := false
+	for _, rt := range s.routes {
+		params, ok := match(rt.pattern, segments)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if rt.method != r.Method {
+			continue
+		}
+		version := r.URL.Query().Get("api-version")
+		if version == "" {
+			writeError(w, http.StatusBadRequest, "MissingApiVersionParameter", "The api-version query parameter is required.")
+			return
+		}
+		if !rt.versions[version] {
+			writeError(w, http.StatusBadRequest, "UnsupportedApiVersionParameter", "The api-version '"+version+"' is not supported for "+r.Method+" "+r.URL.Path+".")
+			return
+		}
+		req := &request{Request: r, params: params, body: body}
+		switch rt.auth {
+		case authKey:
+			if msg := s.verifySignature(r, body); msg != "" {
+				writeError(w, http.StatusUnauthorized, "Denied", msg)
+				return
+			}
+		case authBearer:
+			caller, msg := s.verifyBearer(r)
+			if caller == nil {
+				writeError(w, http.StatusUnauthorized, "InvalidAuthenticationToken", msg)
+				return
+			}
+			req.caller = caller
+		}
+		s.serveRepeatable(w, req, rt.handler)
+		return
+	}
+	if pathMatched {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported on "+r.URL.Path)
+		return
+	}
+	writeError(w, http.StatusNotFound, "NotFound", "no route for "+r.URL.Path)
+}
+
+// recordedResponse is a response kept for replaying a repeated request.
+type recordedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	recorded recordedResponse
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.recorded.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.recorded.status == 0 {
+		r.recorded.status = http.StatusOK
+	}
+	r.recorded.body = append(r.recorded.body, b...)
+	return r.ResponseWriter.Write(b)
+}
+
+// serveRepeatable runs handler, or replays its earlier response when the
+// request repeats a Repeatability-Request-ID already seen for the same
+// operation, the way ACS deduplicates retried creations.
+func (s *Server) serveRepeatable(
+	w http.ResponseWriter,
+	r *request,
+	handler func(w http.ResponseWriter, r *request),
+) {
+	id := r.Header.Get("Repeatability-Request-ID")
+	if id == "" {
+		handler(w, r)
+		return
+	}
+	if firstSent := r.Header.Get("Repeatability-First-Sent"); firstSent != "" {
+		if _, err := http.ParseTime(firstSent); err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "malformed Repeatability-First-Sent header")
+			return
+		}
+	}
+	key := r.Method + " " + r.URL.Path + " " + id
+	s.mu.Lock()
+	recorded, ok := s.replays[key]
+	s.mu.Unlock()
+	if ok {
+		for k, v := range recorded.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(recorded.status)
+		w.Write(recorded.body)
+		return
+	}
+	w.Header().Set("Repeatability-Result", "accepted")
+	recorder := &responseRecorder{ResponseWriter: w}
+	handler(recorder, r)
+	if recorder.recorded.status >= 200 && recorder.recorded.status < 300 {
+		recorder.recorded.header = w.Header().Clone()
+		s.mu.Lock()
+		s.replays[key] = &recorder.recorded
+		s.mu.Unlock()
+	}
+}
+
+func match(pattern []string, segments []string) ([]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := []string{}
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			params = append(params, segments[i])
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// verifySignature checks an access-key request the way ACS does and returns
+// a description of the first problem found.
+func (s *Server) verifySignature(r *http.Request, body []byte) string {
+	date := r.Header.Get("X-Ms-Date")
+	if date == "" {
+		return "missing x-ms-date header"
+	}
+	signedAt, err := http.ParseTime(date)
+	if err != nil {
+		return "malformed x-ms-date header"
+	}
+	if skew := time.Since(signedAt); skew > maxClockSkew || skew < -maxClockSkew {
+		return "x-ms-date is too far from the server time"
+	}
+	sum := sha256.Sum256(body)
+	contentHash := base64.StdEncoding.EncodeToString(sum[:])
+	if r.Header.Get("X-Ms-Content-Sha256") != contentHash {
+		return "x-ms-content-sha256 does not match the request body"
+	}
+	stringToSign := fmt.Sprintf("%s\n%s\n%s;%s;%s", r.Method, r.URL.RequestURI(), date, r.Host, contentHash)
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(stringToSign))
+	want := "HMAC-SHA256 SignedHeaders=x-ms-date;host;x-ms-content-sha256&Signature=" +
+		base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(r.Header.Get("Authorization")), []byte(want)) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+func (s *Server) verifyBearer(r *http.Request) (*identityRecord, string) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, "missing bearer token"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[strings.TrimPrefix(auth, "Bearer ")]
+	if !ok {
+		return nil, "unknown or revoked token"
+	}
+	if !time.Now().Before(token.expiresOn) {
+		return nil, "token expired"
+	}
+	caller, ok := s.identities[token.identityID]
+	if !ok {
+		return nil, "identity was deleted"
+	}
+	return caller, ""
+}
+
+// nextID returns a unique, increasing id, guarded by s.mu.
+func (s *Server) nextID() int {
+	s.seq++
+	return s.seq
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// mergePatch rejects r unless it is sent as application/merge-patch+json.
+func mergePatch(w http.ResponseWriter, r *request) bool {
+	if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+		writeError(w, http.StatusUnsupportedMediaType, "UnsupportedMediaType", "updates must be sent as application/merge-patch+json")
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,106 @@
+package acstest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedRequestIsAccepted(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := client.New(srv.Key(), srv.ClientOptions()...)
+	var response map[string]interface{}
+	err := c.Post(context.Background(), srv.Host(), "/identities", "api-version=2022-10-01", nil, &response)
+	assert.Nil(t, err)
+	assert.Contains(t, response, "identity")
+}
+
+func TestWrongKeyIsRejected(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := client.New("d3Jvbmc=", srv.ClientOptions()...)
+	err := c.Post(context.Background(), srv.Host(), "/identities", "api-version=2022-10-01", nil, nil)
+	assert.True(t, errors.Is(err, client.ERR_UNAUTHORIZED))
+}
+
+func TestMissingAPIVersionIsRejected(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := client.New(srv.Key(), srv.ClientOptions()...)
+	err := c.Post(context.Background(), srv.Host(), "/identities", "", nil, nil)
+	assert.True(t, client.HasErrorCode(err, "MissingApiVersionParameter"))
+}
+
+func TestBearerTokenIsChecked(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	id := srv.CreateIdentity()
+	token := srv.IssueToken(id, []string{"chat"}, time.Hour)
+	bearer := client.NewWithCredential(
+		client.NewBearerTokenCredential(func(ctx context.Context) (string, error) {
+			return token, nil
+		}),
+		srv.ClientOptions()...,
+	)
+	var response map[string]interface{}
+	err := bearer.Get(context.Background(), srv.Host(), "/chat/threads", "api-version=2021-09-07", &response)
+	assert.Nil(t, err)
+	assert.Empty(t, response["value"])
+
+	keyed := client.New(srv.Key(), srv.ClientOptions()...)
+	assert.Nil(t, keyed.Post(context.Background(), srv.Host(), "/identities/"+id+"/:revokeAccessTokens", "api-version=2022-10-01", nil, nil))
+	err = bearer.Get(context.Background(), srv.Host(), "/chat/threads", "api-version=2021-09-07", nil)
+	var respErr *client.ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusUnauthorized, respErr.StatusCode)
+}
+
+func TestChatListsArePaged(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	token := srv.IssueToken(srv.CreateIdentity(), []string{"chat"}, time.Hour)
+	c := client.NewWithCredential(
+		client.NewBearerTokenCredential(func(ctx context.Context) (string, error) {
+			return token, nil
+		}),
+		srv.ClientOptions()...,
+	)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, c.Post(context.Background(), srv.Host(), "/chat/threads", "api-version=2021-09-07", map[string]string{"topic": "t"}, nil))
+	}
+	var page struct {
+		NextLink string        `json:"nextLink"`
+		Value    []interface{} `json:"value"`
+	}
+	err := c.Get(context.Background(), srv.Host(), "/chat/threads", "api-version=2021-09-07&maxPageSize=2", &page)
+	assert.Nil(t, err)
+	assert.Len(t, page.Value, 2)
+	assert.Contains(t, page.NextLink, "skip=2")
+}
+
+func TestUnknownAPIVersionIsRejected(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := client.New(srv.Key(), srv.ClientOptions()...)
+	err := c.Post(context.Background(), srv.Host(), "/identities", "api-version=2025-03-02", nil, nil)
+	assert.True(t, client.HasErrorCode(err, "UnsupportedApiVersionParameter"))
+	err = c.Get(context.Background(), srv.Host(), "/rooms", "api-version=2022-02-01", nil)
+	assert.True(t, client.HasErrorCode(err, "UnsupportedApiVersionParameter"))
+	assert.Nil(t, c.Get(context.Background(), srv.Host(), "/rooms", "api-version=2023-06-14", nil))
+}
+
+func TestCustomIDRequiresPreviewIdentityVersion(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := client.New(srv.Key(), srv.ClientOptions()...)
+	body := map[string]string{"customId": "alice"}
+	err := c.Post(context.Background(), srv.Host(), "/identities", "api-version=2023-10-01", body, nil)
+	assert.True(t, client.HasErrorCode(err, "InvalidRequest"))
+	assert.Nil(t, c.Post(context.Background(), srv.Host(), "/identities", "api-version=2025-03-02-preview", body, nil))
+}
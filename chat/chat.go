@@ -3,11 +3,15 @@ package chat
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/karim-w/go-azure-communication-services/client"
+	"github.com/karim-w/go-azure-communication-services/credential"
+	"github.com/karim-w/go-azure-communication-services/identifier"
 	"github.com/karim-w/go-azure-communication-services/identity"
-	"github.com/karim-w/stdlib/httpclient"
 )
 
 type Chat interface {
@@ -34,6 +38,12 @@ type Chat interface {
 		token string,
 		ExpiresAt time.Time,
 	) Chat
+	WithCredential(
+		cred credential.CommunicationTokenCredential,
+	) Chat
+	WithTokenRefreshWindow(
+		window time.Duration,
+	) Chat
 	GetToken() (string, error)
 	SetTokenFetcher(
 		fetcher func() (string, error),
@@ -70,24 +80,80 @@ type Chat interface {
 		ctx context.Context,
 		opts *ListChatParticipantsOptions,
 	) (*ChatParticipantsCollection, error)
+	NewListChatThreadsPager(
+		opts *ListChatThreadsOptions,
+	) *Pager[ChatThreadsItem]
+	NewListChatMessagesPager(
+		opts *ListChatMessagesOptions,
+	) *Pager[ChatMessage]
+	NewListChatParticipantsPager(
+		opts *ListChatParticipantsOptions,
+	) *Pager[ChatParticipant]
+	SendReadReceipt(
+		ctx context.Context,
+		threadID string,
+		messageID string,
+	) error
+	ListReadReceipts(
+		ctx context.Context,
+		threadID string,
+		opts *ListReadReceiptsOptions,
+	) (*ChatMessageReadReceiptsCollection, error)
+	NewListReadReceiptsPager(
+		threadID string,
+		opts *ListReadReceiptsOptions,
+	) *Pager[ChatMessageReadReceipt]
+	SendTypingNotification(
+		ctx context.Context,
+		threadID string,
+		opts *SendTypingNotificationOptions,
+	) error
+	WithTypingNotificationWindow(
+		window time.Duration,
+	) Chat
+	GetChatThreadProperties(
+		ctx context.Context,
+		threadID string,
+	) (*ChatThreadProperties, error)
+	UpdateChatThreadTopic(
+		ctx context.Context,
+		threadID string,
+		topic string,
+	) error
+	UpdateChatThreadProperties(
+		ctx context.Context,
+		threadID string,
+		opts *UpdateChatThreadOptions,
+	) error
 }
 
 type _chat struct {
-	host         string
-	client       *client.Client
-	token        string
-	validUntil   time.Time
-	idc          *identity.Identity
-	id           string
-	tokenFetcher *func() (string, error)
+	host   string
+	client *client.Client
+
+	// mu guards cred, which WithToken and SetTokenFetcher may swap while
+	// requests are in flight, and the refreshWindow handed to the
+	// credentials SetTokenFetcher creates.
+	mu            sync.Mutex
+	cred          credential.CommunicationTokenCredential
+	refreshWindow time.Duration
+
+	// typingMu guards typingWindow and lastTyping, the time the last
+	// typing notification was sent to each thread.
+	typingMu     sync.Mutex
+	typingWindow time.Duration
+	lastTyping   map[string]time.Time
 }
 
-func New(host string, key string) (Chat, error) {
-	identityClient := identity.New(host, key)
+// New provisions a dedicated ACS identity and authenticates as it, issuing
+// fresh chat and voip tokens before the current one expires. host is
+// either the resource host or a full endpoint URL; see client.ParseEndpoint.
+func New(host string, key string, opts ...client.Option) (Chat, error) {
+	identityClient := identity.New(host, key, opts...)
 	user, err := identityClient.CreateIdentity(
 		context.Background(),
 		&identity.CreateIdentityOptions{
-			CreateTokenWithScopes: []string{"chat", "voip"},
+			CreateTokenWithScopes: []identity.TokenScope{identity.ScopeChat, identity.ScopeVoIP},
 			ExpiresInMinutes:      1440,
 		},
 	)
@@ -97,75 +163,168 @@ func New(host string, key string) (Chat, error) {
 	if user == nil {
 		return nil, fmt.Errorf("failed to create identity")
 	}
-	client := client.New(key)
-	return &_chat{
-		host:       host,
-		client:     client,
-		token:      user.Token,
-		validUntil: user.ExpiresOn,
-		idc:        &identityClient,
-		id:         user.ID,
-	}, nil
-}
-
-func (c *_chat) refreshToken() error {
-	client := *c.idc
-	user, err := client.IssueAccessToken(
-		context.Background(),
-		c.id,
+	cred := identity.NewTokenCredential(
+		identityClient,
+		user.ID,
 		&identity.IssueTokenOptions{
+			Scopes:           []identity.TokenScope{identity.ScopeChat, identity.ScopeVoIP},
 			ExpiresInMinutes: 1440,
-			Scopes:           []string{"chat", "void"},
+		},
+		&credential.RefreshOptions{
+			InitialToken: &credential.AccessToken{
+				Token:     user.Token,
+				ExpiresOn: user.ExpiresOn,
+			},
 		},
 	)
+	return NewWithCredential(host, cred, opts...)
+}
+
+// NewFromConnectionString creates a chat client from an ACS connection
+// string, provisioning its own identity like New.
+func NewFromConnectionString(
+	connectionString string,
+	opts ...client.Option,
+) (Chat, error) {
+	cs, err := client.ParseConnectionString(connectionString)
 	if err != nil {
-		return err
-	}
-	if user == nil {
-		return fmt.Errorf("failed to create identity")
+		return nil, err
 	}
-	c.token = user.Token
-	c.validUntil = user.ExpiresOn
-	return nil
+	return New(cs.Endpoint, cs.AccessKey, opts...)
 }
 
-func NewWithToken(host string, token string, expiresAt time.Time) (Chat, error) {
-	return &_chat{
-		host:       host,
-		client:     nil,
-		token:      token,
-		validUntil: expiresAt,
-	}, nil
+func NewWithToken(
+	host string,
+	token string,
+	expiresAt time.Time,
+	opts ...client.Option,
+) (Chat, error) {
+	return NewWithCredential(
+		host,
+		credential.NewStaticTokenCredentialWithExpiry(token, expiresAt),
+		opts...,
+	)
 }
 
-func (c *_chat) getToken() (string, error) {
-	if time.Now().After(c.validUntil) {
-		if c.idc != nil && c.id != "" {
-			err := c.refreshToken()
-			if err != nil {
-				return c.token, err
-			} else {
-				return "", ERR_EXPIRED_TOKEN
-			}
-		}
-	}
-	if c.token == "" {
+// NewWithCredential creates a chat client that authenticates every request
+// with a token from cred.
+func NewWithCredential(
+	host string,
+	cred credential.CommunicationTokenCredential,
+	opts ...client.Option,
+) (Chat, error) {
+	c := &_chat{
+		host:         host,
+		cred:         cred,
+		typingWindow: DefaultTypingNotificationWindow,
+	}
+	c.client = client.NewWithCredential(
+		client.NewBearerTokenCredential(c.getToken),
+		opts...,
+	)
+	return c, nil
+}
+
+func (c *_chat) getToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	cred := c.cred
+	c.mu.Unlock()
+	if cred == nil {
 		return "", ERR_NO_TOKEN_PROVIDED
 	}
-	return c.token, nil
+	token, err := cred.GetToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.Token, nil
 }
 
 func (c *_chat) GetToken() (string, error) {
-	if c.tokenFetcher != nil {
-		return (*c.tokenFetcher)()
-	}
-	return c.getToken()
+	return c.getToken(context.Background())
 }
 
+// SetTokenFetcher authenticates with tokens from fetcher. Tokens whose exp
+// claim can be decoded are reused until they near expiry; others are
+// fetched again for every request.
 func (c *_chat) SetTokenFetcher(
 	fetcher func() (string, error),
 ) {
-	c.tokenFetcher = &fetcher
+	c.mu.Lock()
+	window := c.refreshWindow
+	c.mu.Unlock()
+	c.WithCredential(credential.NewRefreshingTokenCredential(
+		func(ctx context.Context) (credential.AccessToken, error) {
+			token, err := fetcher()
+			if err != nil {
+				return credential.AccessToken{}, err
+			}
+			return credential.AccessToken{Token: token}, nil
+		},
+		&credential.RefreshOptions{RefreshWindow: window},
+	))
+}
+
+func (c *_chat) WithCredential(
+	cred credential.CommunicationTokenCredential,
+) Chat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cred = cred
+	return c
+}
+
+// WithTokenRefreshWindow sets how long before expiry the token is
+// refreshed. It applies to the current credential when it refreshes tokens,
+// as the one New creates does, and to those SetTokenFetcher creates later.
+// The default is 10 minutes.
+func (c *_chat) WithTokenRefreshWindow(
+	window time.Duration,
+) Chat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshWindow = window
+	if cred, ok := c.cred.(credential.RefreshWindowSetter); ok {
+		cred.SetRefreshWindow(window)
+	}
+	return c
+}
+
+// send issues a bearer-authorized request against the chat endpoint and
+// decodes a successful response into response when it is non-nil. Service
+// failures are returned as *client.ResponseError.
+func (c *_chat) send(
+	ctx context.Context,
+	method string,
+	resource string,
+	query string,
+	contentType string,
+	reqbody interface{},
+	response interface{},
+) error {
+	header := http.Header{}
+	if reqbody != nil {
+		header.Set("Content-Type", contentType)
+	}
+	return c.do(ctx, &client.Request{
+		Method: method,
+		Path:   resource,
+		Query:  query,
+		Header: header,
+		Body:   reqbody,
+	}, response)
+}
+
+func (c *_chat) do(
+	ctx context.Context,
+	req *client.Request,
+	response interface{},
+) error {
+	req.Host = c.host
+	res, err := c.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return res.Decode(response)
 }
 
 func (c *_chat) CreateChatThread(
@@ -173,77 +332,59 @@ func (c *_chat) CreateChatThread(
 	topic string,
 	participants ...ChatUser,
 ) (*CreateChatThreadResponse, error) {
-	token, err := c.GetToken()
-	if err != nil {
-		return nil, err
+	// The id goes in the body as well as the headers, so the service
+	// recognizes a retried creation however it deduplicates.
+	requestID := client.RepeatabilityRequestID(ctx)
+	if requestID == "" {
+		requestID = client.NewRepeatabilityRequestID()
+		ctx = client.WithRepeatabilityRequestID(ctx, requestID)
 	}
 	req := CreateChatThread{
-		Topic: topic,
+		Topic:                  topic,
+		RepeatabilityRequestID: requestID,
 	}
 	for _, p := range participants {
 		req.Participants = append(req.Participants, Participant{
-			CommunicationIdentifier: identity.CommunicationIdentifier{
-				RawID: p.ID,
-				CommunicationUser: identity.CommunicationUser{
-					ID: p.ID,
-				},
-			},
-			DisplayName: p.DisplayName,
+			CommunicationIdentifier: identifier.FromRawID(p.ID),
+			DisplayName:             p.DisplayName,
 		})
 	}
 	response := CreateChatThreadResponse{}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").
-		AddBody(req).Post()
-	if res.IsSuccess() {
-		err := res.SetResult(&response)
-		if err != nil {
-			return nil, err
-		}
-		return &response, nil
-	}
-	if res.GetStatusCode() == 401 {
-		return nil, ERR_UNAUTHORIZED
+	err := c.do(ctx, &client.Request{
+		Method:     http.MethodPost,
+		Path:       "/chat/threads",
+		Query:      "api-version=" + _apiVersion,
+		Body:       req,
+		Repeatable: true,
+	}, &response)
+	if err != nil {
+		return nil, err
 	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return nil, err
+	return &response, nil
 }
 
 func (c *_chat) DeleteChatThread(
 	ctx context.Context,
 	threadID string,
 ) error {
-	token, err := c.GetToken()
-	if err != nil {
-		return err
-	}
-
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+threadID+"?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").
-		Del()
-	if res.IsSuccess() {
-		return nil
-	}
-	if res.GetStatusCode() == 401 {
-		return ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return err
+	return c.send(
+		ctx,
+		http.MethodDelete,
+		"/chat/threads/"+url.PathEscape(threadID),
+		"api-version="+_apiVersion,
+		"application/json",
+		nil,
+		nil,
+	)
 }
 
 func (c *_chat) WithToken(
 	token string,
 	ExpiresAt time.Time,
 ) Chat {
-	c.token = token
-	c.validUntil = ExpiresAt
-	return c
+	return c.WithCredential(
+		credential.NewStaticTokenCredentialWithExpiry(token, ExpiresAt),
+	)
 }
 
 func (c *_chat) AddChatParticipants(
@@ -251,38 +392,24 @@ func (c *_chat) AddChatParticipants(
 	threadID string,
 	participants ...ChatUser,
 ) error {
-	token, err := c.GetToken()
-	if err != nil {
-		return err
-	}
 	req := []Participant{}
 	for _, p := range participants {
 		req = append(req, Participant{
-			CommunicationIdentifier: identity.CommunicationIdentifier{
-				RawID: p.ID,
-				CommunicationUser: identity.CommunicationUser{
-					ID: p.ID,
-				},
-			},
-			DisplayName: p.DisplayName,
+			CommunicationIdentifier: identifier.FromRawID(p.ID),
+			DisplayName:             p.DisplayName,
 		})
 	}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+threadID+"/participants/:add?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").
-		AddBody(map[string]interface{}{
+	return c.send(
+		ctx,
+		http.MethodPost,
+		"/chat/threads/"+url.PathEscape(threadID)+"/participants/:add",
+		"api-version="+_apiVersion,
+		"application/json",
+		map[string]interface{}{
 			"participants": req,
-		}).Post()
-	if res.IsSuccess() {
-		return nil
-	}
-	if res.GetStatusCode() == 401 {
-		return ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return err
+		},
+		nil,
+	)
 }
 
 func (c *_chat) RemoveChatParticipant(
@@ -290,40 +417,21 @@ func (c *_chat) RemoveChatParticipant(
 	threadID string,
 	acsId string,
 ) error {
-	token, err := c.GetToken()
-	if err != nil {
-		return err
-	}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+threadID+"/participants/:remove?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").
-		AddBody(identity.CommunicationIdentifier{
-			RawID: acsId,
-			CommunicationUser: identity.CommunicationUser{
-				ID: acsId,
-			},
-		}).Post()
-	if res.IsSuccess() {
-		return nil
-	}
-	if res.GetStatusCode() == 401 {
-		return ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return err
+	return c.send(
+		ctx,
+		http.MethodPost,
+		"/chat/threads/"+url.PathEscape(threadID)+"/participants/:remove",
+		"api-version="+_apiVersion,
+		"application/json",
+		identifier.FromRawID(acsId),
+		nil,
+	)
 }
 
 func (c *_chat) SendChatMessage(
 	ctx context.Context,
 	opts *SendChatMessageOptions,
 ) (*SendChatMessageResponse, error) {
-	token, err := c.GetToken()
-	if err != nil {
-		return nil, err
-	}
-
 	req := SendChatMessageRequest{
 		Content:           opts.Request.Content,
 		Metadata:          opts.Request.Metadata,
@@ -332,25 +440,17 @@ func (c *_chat) SendChatMessage(
 	}
 
 	response := SendChatMessageResponse{}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+opts.ChatThreadId+"/messages?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").
-		AddBody(req).Post()
-
-	if res.IsSuccess() {
-		err := res.SetResult(&response)
-		if err != nil {
-			return nil, err
-		}
-		return &response, nil
-	}
-	if res.GetStatusCode() == 401 {
-		return nil, ERR_UNAUTHORIZED
+	err := c.do(ctx, &client.Request{
+		Method:     http.MethodPost,
+		Path:       "/chat/threads/" + url.PathEscape(opts.ChatThreadId) + "/messages",
+		Query:      "api-version=" + _apiVersion,
+		Body:       req,
+		Repeatable: true,
+	}, &response)
+	if err != nil {
+		return nil, err
 	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return nil, err
+	return &response, nil
 }
 
 func (c *_chat) GetChatMessage(
@@ -358,67 +458,40 @@ func (c *_chat) GetChatMessage(
 	messageID string,
 	threadID string,
 ) (*ChatMessage, error) {
-	token, err := c.GetToken()
+	response := ChatMessage{}
+	err := c.send(
+		ctx,
+		http.MethodGet,
+		"/chat/threads/"+url.PathEscape(threadID)+"/messages/"+url.PathEscape(messageID),
+		"api-version="+_apiVersion,
+		"application/json",
+		nil,
+		&response,
+	)
 	if err != nil {
 		return nil, err
 	}
-
-	response := ChatMessage{}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+threadID+"/messages/"+messageID+"?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").Get()
-
-	if res.IsSuccess() {
-		err := res.SetResult(&response)
-		if err != nil {
-			return nil, err
-		}
-		return &response, nil
-	}
-	if res.GetStatusCode() == 401 {
-		return nil, ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return nil, err
+	return &response, nil
 }
 
 func (c *_chat) ListChatMessages(
 	ctx context.Context,
 	opts *ListChatMessagesOptions,
 ) (*ChatMessagesCollection, error) {
-	token, err := c.GetToken()
+	response := ChatMessagesCollection{}
+	err := c.send(
+		ctx,
+		http.MethodGet,
+		"/chat/threads/"+url.PathEscape(opts.ChatThreadId)+"/messages",
+		opts.query(),
+		"application/json",
+		nil,
+		&response,
+	)
 	if err != nil {
 		return nil, err
 	}
-
-	optionalParams := ""
-	if opts.MaxPageSize > 0 {
-		optionalParams += "maxPageSize=" + fmt.Sprint(opts.MaxPageSize) + "&"
-	}
-	if opts.StartTime != "" {
-		optionalParams += "&startTime=" + opts.StartTime + "&"
-	}
-	response := ChatMessagesCollection{}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+opts.ChatThreadId+"/messages?"+optionalParams+"api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").Get()
-
-	if res.IsSuccess() {
-		err := res.SetResult(&response)
-		if err != nil {
-			return nil, err
-		}
-		return &response, nil
-	}
-	if res.GetStatusCode() == 401 {
-		return nil, ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return nil, err
+	return &response, nil
 }
 
 func (c *_chat) DeleteChatMessage(
@@ -426,25 +499,15 @@ func (c *_chat) DeleteChatMessage(
 	messageID string,
 	threadID string,
 ) error {
-	token, err := c.GetToken()
-	if err != nil {
-		return err
-	}
-
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+threadID+"/messages/"+messageID+"?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").
-		Del()
-	if res.IsSuccess() {
-		return nil
-	}
-	if res.GetStatusCode() == 401 {
-		return ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return err
+	return c.send(
+		ctx,
+		http.MethodDelete,
+		"/chat/threads/"+url.PathEscape(threadID)+"/messages/"+url.PathEscape(messageID),
+		"api-version="+_apiVersion,
+		"application/json",
+		nil,
+		nil,
+	)
 }
 
 func (c *_chat) UpdateChatMessages(
@@ -453,103 +516,230 @@ func (c *_chat) UpdateChatMessages(
 	threadID string,
 	opts *UpdateChatMessageOptions,
 ) error {
-	token, err := c.GetToken()
-	if err != nil {
-		return err
-	}
-
 	req := UpdateChatMessageOptions{
 		Content:  opts.Content,
 		Metadata: opts.Metadata,
 	}
-
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+threadID+"/messages/"+messageID+"?api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/merge-patch+json").
-		AddBody(req).Patch()
-	if res.IsSuccess() {
-		return nil
-	}
-	fmt.Println(res)
-	if res.GetStatusCode() == 401 {
-		return ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return err
+	return c.send(
+		ctx,
+		http.MethodPatch,
+		"/chat/threads/"+url.PathEscape(threadID)+"/messages/"+url.PathEscape(messageID),
+		"api-version="+_apiVersion,
+		"application/merge-patch+json",
+		req,
+		nil,
+	)
 }
 
 func (c *_chat) ListChatThreads(
 	ctx context.Context,
 	opts *ListChatThreadsOptions,
 ) (*ChatThreadsItemCollection, error) {
-	token, err := c.GetToken()
+	response := ChatThreadsItemCollection{}
+	err := c.send(
+		ctx,
+		http.MethodGet,
+		"/chat/threads",
+		opts.query(),
+		"application/json",
+		nil,
+		&response,
+	)
 	if err != nil {
 		return nil, err
 	}
-
-	optionalParams := ""
-	if opts.MaxPageSize > 0 {
-		optionalParams += "maxPageSize=" + fmt.Sprint(opts.MaxPageSize) + "&"
-	}
-	if opts.StartTime != "" {
-		optionalParams += "&startTime=" + opts.StartTime + "&"
-	}
-	response := ChatThreadsItemCollection{}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads?"+optionalParams+"api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").Get()
-
-	if res.IsSuccess() {
-		err := res.SetResult(&response)
-		if err != nil {
-			return nil, err
-		}
-		return &response, nil
-	}
-	if res.GetStatusCode() == 401 {
-		return nil, ERR_UNAUTHORIZED
-	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return nil, err
+	return &response, nil
 }
 
 func (c *_chat) ListChatParticipants(
 	ctx context.Context,
 	opts *ListChatParticipantsOptions,
 ) (*ChatParticipantsCollection, error) {
-	token, err := c.GetToken()
+	response := ChatParticipantsCollection{}
+	err := c.send(
+		ctx,
+		http.MethodGet,
+		"/chat/threads/"+url.PathEscape(opts.ChatThreadId)+"/participants",
+		opts.query(),
+		"application/json",
+		nil,
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SendReadReceipt marks messageID, and every message before it, as read by
+// the caller.
+func (c *_chat) SendReadReceipt(
+	ctx context.Context,
+	threadID string,
+	messageID string,
+) error {
+	return c.send(
+		ctx,
+		http.MethodPost,
+		"/chat/threads/"+url.PathEscape(threadID)+"/readReceipts",
+		"api-version="+_apiVersion,
+		"application/json",
+		sendReadReceiptRequest{ChatMessageID: messageID},
+		nil,
+	)
+}
+
+// ListReadReceipts returns the first page of read receipts of a thread,
+// one per participant. Use NewListReadReceiptsPager to follow nextLink.
+func (c *_chat) ListReadReceipts(
+	ctx context.Context,
+	threadID string,
+	opts *ListReadReceiptsOptions,
+) (*ChatMessageReadReceiptsCollection, error) {
+	response := ChatMessageReadReceiptsCollection{}
+	err := c.send(
+		ctx,
+		http.MethodGet,
+		"/chat/threads/"+url.PathEscape(threadID)+"/readReceipts",
+		opts.query(),
+		"application/json",
+		nil,
+		&response,
+	)
 	if err != nil {
 		return nil, err
 	}
+	return &response, nil
+}
 
-	optionalParams := ""
-	if opts.MaxPageSize > 0 {
-		optionalParams += "maxPageSize=" + fmt.Sprint(opts.MaxPageSize) + "&"
+// SendTypingNotification tells the other participants the caller is typing.
+// Calls for the same thread within the typing notification window of the
+// last one sent are coalesced into it and return nil without a request.
+func (c *_chat) SendTypingNotification(
+	ctx context.Context,
+	threadID string,
+	opts *SendTypingNotificationOptions,
+) error {
+	if opts == nil {
+		opts = &SendTypingNotificationOptions{}
 	}
-	if opts.Skip > 0 {
-		optionalParams += "&skip=" + fmt.Sprint(opts.Skip) + "&"
+	sentAt, ok := c.claimTyping(threadID)
+	if !ok {
+		return nil
 	}
-	response := ChatParticipantsCollection{}
-	res := httpclient.Req(
-		"https://"+c.host+"/chat/threads/"+opts.ChatThreadId+"/participants?"+optionalParams+"api-version="+_apiVersion,
-	).AddBearerAuth(
-		token,
-	).AddHeader("Content-Type", "application/json").Get()
-
-	if res.IsSuccess() {
-		err := res.SetResult(&response)
-		if err != nil {
-			return nil, err
+	err := c.send(
+		ctx,
+		http.MethodPost,
+		"/chat/threads/"+url.PathEscape(threadID)+"/typing",
+		"api-version="+_apiVersion,
+		"application/json",
+		sendTypingNotificationRequest{SenderDisplayName: opts.SenderDisplayName},
+		nil,
+	)
+	if err != nil {
+		c.releaseTyping(threadID, sentAt)
+	}
+	return err
+}
+
+// WithTypingNotificationWindow sets how long after a typing notification
+// further ones for the same thread are coalesced. Zero disables the
+// throttle.
+func (c *_chat) WithTypingNotificationWindow(
+	window time.Duration,
+) Chat {
+	c.typingMu.Lock()
+	defer c.typingMu.Unlock()
+	c.typingWindow = window
+	return c
+}
+
+// claimTyping reserves the right to send a typing notification to
+// threadID, so concurrent callers do not both send one. Claims older than
+// the window no longer throttle anything and are dropped, so lastTyping
+// only holds the threads typed in recently.
+func (c *_chat) claimTyping(threadID string) (time.Time, bool) {
+	c.typingMu.Lock()
+	defer c.typingMu.Unlock()
+	now := time.Now()
+	for id, last := range c.lastTyping {
+		if now.Sub(last) >= c.typingWindow {
+			delete(c.lastTyping, id)
 		}
-		return &response, nil
 	}
-	if res.GetStatusCode() == 401 {
-		return nil, ERR_UNAUTHORIZED
+	if c.typingWindow <= 0 {
+		return now, true
 	}
-	err = fmt.Errorf(string(res.GetBody()))
-	return nil, err
+	if last, ok := c.lastTyping[threadID]; ok && now.Sub(last) < c.typingWindow {
+		return time.Time{}, false
+	}
+	if c.lastTyping == nil {
+		c.lastTyping = map[string]time.Time{}
+	}
+	c.lastTyping[threadID] = now
+	return now, true
+}
+
+// releaseTyping forgets a claim whose notification failed, so the next
+// call sends again.
+func (c *_chat) releaseTyping(threadID string, sentAt time.Time) {
+	c.typingMu.Lock()
+	defer c.typingMu.Unlock()
+	if c.lastTyping[threadID].Equal(sentAt) {
+		delete(c.lastTyping, threadID)
+	}
+}
+
+func (c *_chat) GetChatThreadProperties(
+	ctx context.Context,
+	threadID string,
+) (*ChatThreadProperties, error) {
+	response := ChatThreadProperties{}
+	err := c.send(
+		ctx,
+		http.MethodGet,
+		"/chat/threads/"+url.PathEscape(threadID),
+		"api-version="+_apiVersion,
+		"application/json",
+		nil,
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *_chat) UpdateChatThreadTopic(
+	ctx context.Context,
+	threadID string,
+	topic string,
+) error {
+	if topic == "" {
+		return ERR_EMPTY_TOPIC
+	}
+	return c.UpdateChatThreadProperties(ctx, threadID, &UpdateChatThreadOptions{
+		Topic: topic,
+	})
+}
+
+// UpdateChatThreadProperties changes the topic, metadata or retention
+// policy of a thread, leaving fields that are not set in opts untouched.
+func (c *_chat) UpdateChatThreadProperties(
+	ctx context.Context,
+	threadID string,
+	opts *UpdateChatThreadOptions,
+) error {
+	if opts == nil {
+		opts = &UpdateChatThreadOptions{}
+	}
+	return c.send(
+		ctx,
+		http.MethodPatch,
+		"/chat/threads/"+url.PathEscape(threadID),
+		"api-version="+_apiVersion,
+		"application/merge-patch+json",
+		opts,
+		nil,
+	)
 }
@@ -2,21 +2,42 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/karim-w/go-azure-communication-services/acstest"
+	acsclient "github.com/karim-w/go-azure-communication-services/client"
+	"github.com/karim-w/go-azure-communication-services/credential"
+	"github.com/karim-w/go-azure-communication-services/identity"
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	host     = ""
-	key      = ""
-	id       = ""
-	id2      = ""
-	token    = ""
-	threadId = ""
-)
+// newTestChat returns a chat client authenticated as a fresh identity of a
+// fake ACS resource, together with ids of two other identities.
+func newTestChat(t *testing.T) (Chat, *acstest.Server, string, string) {
+	srv := acstest.NewServer()
+	t.Cleanup(srv.Close)
+	caller := srv.CreateIdentity()
+	client, err := NewWithToken(
+		srv.Host(),
+		srv.IssueToken(caller, []string{"chat"}, time.Hour),
+		time.Now().Add(time.Hour),
+		srv.ClientOptions()...,
+	)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return client, srv, srv.CreateIdentity(), srv.CreateIdentity()
+}
 
 func TestTokenSetter(t *testing.T) {
 	c := _chat{}
@@ -24,7 +45,7 @@ func TestTokenSetter(t *testing.T) {
 		return "test", nil
 	}
 	c.SetTokenFetcher(setter)
-	assert.NotNil(t, c.tokenFetcher)
+	assert.NotNil(t, c.cred)
 }
 
 func TestTokenGetter(t *testing.T) {
@@ -50,8 +71,11 @@ func TestTokenGetterWithError(t *testing.T) {
 }
 
 func TestCreateChatThread(t *testing.T) {
-	client, err := New(host, key)
+	srv := acstest.NewServer()
+	defer srv.Close()
+	client, err := New(srv.Host(), srv.Key(), srv.ClientOptions()...)
 	assert.Nil(t, err)
+	id := srv.CreateIdentity()
 	thread, err := client.CreateChatThread(
 		context.Background(),
 		"test",
@@ -63,11 +87,12 @@ func TestCreateChatThread(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, thread)
 	assert.NotEmpty(t, thread.ChatThread.ID)
+	assert.Equal(t, "test", thread.ChatThread.Topic)
+	assert.Empty(t, thread.InvalidParticipants)
 }
 
 func TestDeleteChatThread(t *testing.T) {
-	client, err := New(host, key)
-	assert.Nil(t, err)
+	client, _, id, _ := newTestChat(t)
 	thread, err := client.CreateChatThread(
 		context.Background(),
 		"test",
@@ -84,11 +109,15 @@ func TestDeleteChatThread(t *testing.T) {
 		thread.ChatThread.ID,
 	)
 	assert.Nil(t, err)
+	err = client.DeleteChatThread(
+		context.Background(),
+		thread.ChatThread.ID,
+	)
+	assert.True(t, acsclient.HasErrorCode(err, "NotFound"))
 }
 
 func TestAddChatParticipant(t *testing.T) {
-	client, err := NewWithToken(host, token, time.Now().Add(time.Hour))
-	assert.Nil(t, err)
+	client, _, id, id2 := newTestChat(t)
 	thread, err := client.CreateChatThread(
 		context.Background(),
 		"test",
@@ -109,11 +138,16 @@ func TestAddChatParticipant(t *testing.T) {
 		},
 	)
 	assert.Nil(t, err)
+	participants, err := client.ListChatParticipants(
+		context.Background(),
+		&ListChatParticipantsOptions{ChatThreadId: thread.ChatThread.ID},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, participants.Value, 3)
 }
 
 func TestRemoveChatParticipant(t *testing.T) {
-	client, err := NewWithToken(host, token, time.Now().Add(time.Hour))
-	assert.Nil(t, err)
+	client, _, id, id2 := newTestChat(t)
 	thread, err := client.CreateChatThread(
 		context.Background(),
 		"test",
@@ -140,4 +174,431 @@ func TestRemoveChatParticipant(t *testing.T) {
 		id,
 	)
 	assert.Nil(t, err)
+	participants, err := client.ListChatParticipants(
+		context.Background(),
+		&ListChatParticipantsOptions{ChatThreadId: thread.ChatThread.ID},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, participants.Value, 2)
+}
+
+func TestChatMessages(t *testing.T) {
+	client, _, _, _ := newTestChat(t)
+	thread, err := client.CreateChatThread(context.Background(), "test")
+	assert.Nil(t, err)
+	threadID := thread.ChatThread.ID
+	sent, err := client.SendChatMessage(context.Background(), &SendChatMessageOptions{
+		ChatThreadId: threadID,
+		Request: SendChatMessageRequest{
+			Content:           "hello",
+			SenderDisplayName: "bot",
+			Type:              ChatMessageType_Text,
+		},
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, sent.ID)
+	err = client.UpdateChatMessages(
+		context.Background(),
+		sent.ID,
+		threadID,
+		&UpdateChatMessageOptions{Content: "hello again"},
+	)
+	assert.Nil(t, err)
+	msg, err := client.GetChatMessage(context.Background(), sent.ID, threadID)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello again", msg.Content.Message)
+	assert.Equal(t, "bot", msg.SenderDisplayName)
+	assert.NotEmpty(t, msg.EditedOn)
+	messages, err := client.ListChatMessages(
+		context.Background(),
+		&ListChatMessagesOptions{ChatThreadId: threadID},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, messages.Value, 1)
+	assert.Nil(t, client.DeleteChatMessage(context.Background(), sent.ID, threadID))
+	_, err = client.GetChatMessage(context.Background(), sent.ID, threadID)
+	assert.True(t, acsclient.HasErrorCode(err, "NotFound"))
+}
+
+func TestCreateAndSendAreRepeatable(t *testing.T) {
+	client, _, _, _ := newTestChat(t)
+	ctx := acsclient.WithRepeatabilityRequestID(context.Background(), "thread-key")
+	first, err := client.CreateChatThread(ctx, "test")
+	assert.Nil(t, err)
+	second, err := client.CreateChatThread(ctx, "test")
+	assert.Nil(t, err)
+	assert.Equal(t, first.ChatThread.ID, second.ChatThread.ID)
+
+	threadID := first.ChatThread.ID
+	opts := &SendChatMessageOptions{
+		ChatThreadId: threadID,
+		Request:      SendChatMessageRequest{Content: "hello"},
+	}
+	ctx = acsclient.WithRepeatabilityRequestID(context.Background(), "message-key")
+	sent, err := client.SendChatMessage(ctx, opts)
+	assert.Nil(t, err)
+	resent, err := client.SendChatMessage(ctx, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, sent.ID, resent.ID)
+	other, err := client.SendChatMessage(context.Background(), opts)
+	assert.Nil(t, err)
+	assert.NotEqual(t, sent.ID, other.ID)
+	messages, err := client.ListChatMessages(
+		context.Background(),
+		&ListChatMessagesOptions{ChatThreadId: threadID},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, messages.Value, 2)
+}
+
+func TestCreateChatThreadSendsRepeatabilityRequestID(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(acsclient.HeaderRepeatabilityRequestID)
+		assert.NotEmpty(t, id)
+		assert.NotEmpty(t, r.Header.Get(acsclient.HeaderRepeatabilityFirstSent))
+		assert.Contains(t, readBody(r), `"repeatabilityRequestId":"`+id+`"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"chatThread":{"id":"thread"}}`))
+	}))
+	defer srv.Close()
+	client, err := NewWithToken(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"token",
+		time.Now().Add(time.Hour),
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	assert.Nil(t, err)
+	_, err = client.CreateChatThread(context.Background(), "topic")
+	assert.Nil(t, err)
+}
+
+func newParticipantChat(t *testing.T, srv *acstest.Server, id string) Chat {
+	client, err := NewWithToken(
+		srv.Host(),
+		srv.IssueToken(id, []string{"chat"}, time.Hour),
+		time.Now().Add(time.Hour),
+		srv.ClientOptions()...,
+	)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return client
+}
+
+func TestReadReceipts(t *testing.T) {
+	client, srv, id, _ := newTestChat(t)
+	thread, err := client.CreateChatThread(context.Background(), "test", ChatUser{ID: id})
+	assert.Nil(t, err)
+	threadID := thread.ChatThread.ID
+	sent, err := client.SendChatMessage(context.Background(), &SendChatMessageOptions{
+		ChatThreadId: threadID,
+		Request:      SendChatMessageRequest{Content: "hello"},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, client.SendReadReceipt(context.Background(), threadID, sent.ID))
+	other := newParticipantChat(t, srv, id)
+	assert.Nil(t, other.SendReadReceipt(context.Background(), threadID, sent.ID))
+
+	page, err := client.ListReadReceipts(context.Background(), threadID, &ListReadReceiptsOptions{MaxPageSize: 1})
+	assert.Nil(t, err)
+	assert.Len(t, page.Value, 1)
+	assert.NotEmpty(t, page.NextLink)
+
+	receipts, err := client.NewListReadReceiptsPager(threadID, &ListReadReceiptsOptions{MaxPageSize: 1}).All(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, receipts, 2)
+	assert.Equal(t, sent.ID, receipts[1].ChatMessageID)
+	assert.Equal(t, id, receipts[1].SenderCommunicationIdentifier.RawID())
+	assert.WithinDuration(t, time.Now(), receipts[1].ReadOn, time.Minute)
+
+	err = client.SendReadReceipt(context.Background(), threadID, "missing")
+	assert.True(t, acsclient.HasErrorCode(err, "NotFound"))
+}
+
+func TestTypingNotificationsAreThrottled(t *testing.T) {
+	client, srv, _, _ := newTestChat(t)
+	client = client.WithTypingNotificationWindow(100 * time.Millisecond)
+	thread, err := client.CreateChatThread(context.Background(), "test")
+	assert.Nil(t, err)
+	threadID := thread.ChatThread.ID
+	opts := &SendTypingNotificationOptions{SenderDisplayName: "bot"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, client.SendTypingNotification(context.Background(), threadID, opts))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, srv.TypingNotifications(threadID))
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Nil(t, client.SendTypingNotification(context.Background(), threadID, nil))
+	assert.Equal(t, 2, srv.TypingNotifications(threadID))
+}
+
+func TestFailedTypingNotificationIsNotThrottled(t *testing.T) {
+	client, srv, _, _ := newTestChat(t)
+	err := client.SendTypingNotification(context.Background(), "19:missing@thread.v2", nil)
+	assert.True(t, acsclient.HasErrorCode(err, "NotFound"))
+	thread, err := client.CreateChatThread(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Nil(t, client.SendTypingNotification(context.Background(), thread.ChatThread.ID, nil))
+	err = client.SendTypingNotification(context.Background(), "19:missing@thread.v2", nil)
+	assert.True(t, acsclient.HasErrorCode(err, "NotFound"))
+	assert.Equal(t, 1, srv.TypingNotifications(thread.ChatThread.ID))
+}
+
+func TestTypingClaimsExpire(t *testing.T) {
+	c := &_chat{typingWindow: 20 * time.Millisecond}
+	for i := 0; i < 10; i++ {
+		_, ok := c.claimTyping(fmt.Sprintf("19:thread%d@thread.v2", i))
+		assert.True(t, ok)
+	}
+	assert.Len(t, c.lastTyping, 10)
+	time.Sleep(30 * time.Millisecond)
+	_, ok := c.claimTyping("19:thread0@thread.v2")
+	assert.True(t, ok)
+	assert.Len(t, c.lastTyping, 1)
+}
+
+func TestChatThreadProperties(t *testing.T) {
+	client, _, _, _ := newTestChat(t)
+	thread, err := client.CreateChatThread(context.Background(), "old topic")
+	assert.Nil(t, err)
+	threadID := thread.ChatThread.ID
+
+	assert.Nil(t, client.UpdateChatThreadTopic(context.Background(), threadID, "new topic"))
+	err = client.UpdateChatThreadProperties(context.Background(), threadID, &UpdateChatThreadOptions{
+		Metadata: map[string]string{"ticket": "42"},
+		RetentionPolicy: &ChatRetentionPolicy{
+			Kind:                  RetentionPolicyKind_ThreadCreationDate,
+			DeleteThreadAfterDays: 30,
+		},
+	})
+	assert.Nil(t, err)
+
+	props, err := client.GetChatThreadProperties(context.Background(), threadID)
+	assert.Nil(t, err)
+	assert.Equal(t, threadID, props.ID)
+	assert.Equal(t, "new topic", props.Topic)
+	assert.Equal(t, map[string]string{"ticket": "42"}, props.Metadata)
+	assert.Equal(t, 30, props.RetentionPolicy.DeleteThreadAfterDays)
+	assert.WithinDuration(t, time.Now(), props.CreatedOn, time.Minute)
+	assert.True(t, props.DeletedOn.IsZero())
+	assert.NotEmpty(t, props.CreatedByCommunicationIdentifier.RawID())
+
+	err = client.UpdateChatThreadProperties(context.Background(), threadID, &UpdateChatThreadOptions{
+		RemoveRetentionPolicy: true,
+	})
+	assert.Nil(t, err)
+	props, err = client.GetChatThreadProperties(context.Background(), threadID)
+	assert.Nil(t, err)
+	assert.Nil(t, props.RetentionPolicy)
+	assert.Equal(t, "new topic", props.Topic)
+	assert.Equal(t, map[string]string{"ticket": "42"}, props.Metadata)
+
+	messages, err := client.ListChatMessages(
+		context.Background(),
+		&ListChatMessagesOptions{ChatThreadId: threadID},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, messages.Value, 1)
+	assert.Equal(t, ChatMessageType(ChatMessageType_TopicUpdated), messages.Value[0].Type)
+	assert.Equal(t, "new topic", messages.Value[0].Content.Topic)
+
+	assert.Equal(t, ERR_EMPTY_TOPIC, client.UpdateChatThreadTopic(context.Background(), threadID, ""))
+}
+
+func TestUpdateChatThreadOptionsIsAMergePatch(t *testing.T) {
+	body, err := json.Marshal(&UpdateChatThreadOptions{Topic: "t"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"topic":"t"}`, string(body))
+	body, err = json.Marshal(&UpdateChatThreadOptions{RemoveRetentionPolicy: true})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"retentionPolicy":null}`, string(body))
+}
+
+func TestNonParticipantIsForbidden(t *testing.T) {
+	client, srv, _, _ := newTestChat(t)
+	thread, err := client.CreateChatThread(context.Background(), "test")
+	assert.Nil(t, err)
+	outsider, err := NewWithToken(
+		srv.Host(),
+		srv.IssueToken(srv.CreateIdentity(), []string{"chat"}, time.Hour),
+		time.Now().Add(time.Hour),
+		srv.ClientOptions()...,
+	)
+	assert.Nil(t, err)
+	err = outsider.DeleteChatThread(context.Background(), thread.ChatThread.ID)
+	var respErr *acsclient.ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusForbidden, respErr.StatusCode)
+}
+
+func TestUnknownTokenIsUnauthorized(t *testing.T) {
+	srv := acstest.NewServer()
+	defer srv.Close()
+	client, err := NewWithToken(srv.Host(), "forged", time.Now().Add(time.Hour), srv.ClientOptions()...)
+	assert.Nil(t, err)
+	_, err = client.ListChatThreads(context.Background(), &ListChatThreadsOptions{})
+	assert.True(t, errors.Is(err, ERR_UNAUTHORIZED))
+}
+
+func TestChatHonorsCanceledContext(t *testing.T) {
+	client, err := NewWithToken("127.0.0.1:1", "token", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetChatMessage(ctx, "message", "thread")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestChatWithHTTPClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/chat/threads/thread/messages/message", r.URL.Path)
+		w.Write([]byte(`{"id":"message","type":"text"}`))
+	}))
+	defer srv.Close()
+	client, err := NewWithToken(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"token",
+		time.Now().Add(time.Hour),
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	assert.Nil(t, err)
+	msg, err := client.GetChatMessage(context.Background(), "message", "thread")
+	assert.Nil(t, err)
+	assert.Equal(t, "message", msg.ID)
+}
+
+func newRefreshingChat(
+	t *testing.T,
+	validUntil time.Time,
+	refreshWindow time.Duration,
+	failRefresh bool,
+) (*_chat, *int32) {
+	var refreshes int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/:issueAccessToken") {
+			atomic.AddInt32(&refreshes, 1)
+			assert.Contains(t, readBody(r), `"scopes":["chat","voip"]`)
+			time.Sleep(20 * time.Millisecond)
+			if failRefresh {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"fresh","expiresOn":"` + time.Now().Add(24*time.Hour).UTC().Format(time.RFC3339) + `"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" && r.Header.Get("Authorization") != "Bearer stale" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"message"}`))
+	}))
+	t.Cleanup(srv.Close)
+	host := strings.TrimPrefix(srv.URL, "https://")
+	idc := identity.New(host, "", acsclient.WithHTTPClient(srv.Client()))
+	cred := identity.NewTokenCredential(
+		idc,
+		"8:acs:user",
+		&identity.IssueTokenOptions{
+			Scopes:           []identity.TokenScope{identity.ScopeChat, identity.ScopeVoIP},
+			ExpiresInMinutes: 1440,
+		},
+		&credential.RefreshOptions{
+			InitialToken:  &credential.AccessToken{Token: "stale", ExpiresOn: validUntil},
+			RefreshWindow: refreshWindow,
+		},
+	)
+	c, err := NewWithCredential(host, cred, acsclient.WithHTTPClient(srv.Client()))
+	assert.Nil(t, err)
+	return c.(*_chat), &refreshes
+}
+
+func TestConcurrentCallersShareOneRefresh(t *testing.T) {
+	c, refreshes := newRefreshingChat(t, time.Now().Add(-time.Minute), 0, false)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.SendChatMessage(context.Background(), &SendChatMessageOptions{
+				ChatThreadId: "thread",
+				Request:      SendChatMessageRequest{Content: "hi", Type: ChatMessageType_Text},
+			})
+			assert.Nil(t, err)
+			assert.NotNil(t, res)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(refreshes))
+	token, err := c.GetToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "fresh", token)
+}
+
+func TestProactiveRefreshKeepsServingValidToken(t *testing.T) {
+	c, refreshes := newRefreshingChat(t, time.Now().Add(time.Minute), 0, false)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := c.GetToken()
+			assert.Nil(t, err)
+			assert.Contains(t, []string{"stale", "fresh"}, token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(refreshes))
+	token, _ := c.GetToken()
+	assert.Equal(t, "fresh", token)
+}
+
+func TestRefreshWindowIsConfigurable(t *testing.T) {
+	c, refreshes := newRefreshingChat(t, time.Now().Add(time.Minute), 30*time.Second, false)
+	token, err := c.GetToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "stale", token)
+	assert.Equal(t, int32(0), atomic.LoadInt32(refreshes))
+}
+
+func TestWithTokenRefreshWindow(t *testing.T) {
+	c, refreshes := newRefreshingChat(t, time.Now().Add(time.Minute), 0, false)
+	c.WithTokenRefreshWindow(30 * time.Second)
+	token, err := c.GetToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "stale", token)
+	assert.Equal(t, int32(0), atomic.LoadInt32(refreshes))
+}
+
+func TestFailedRefresh(t *testing.T) {
+	c, _ := newRefreshingChat(t, time.Now().Add(time.Minute), 0, true)
+	token, err := c.GetToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "stale", token)
+
+	c, _ = newRefreshingChat(t, time.Now().Add(-time.Minute), 0, true)
+	token, err = c.GetToken()
+	assert.NotNil(t, err)
+	assert.Equal(t, "", token)
+}
+
+func readBody(r *http.Request) string {
+	body, _ := io.ReadAll(r.Body)
+	return string(body)
+}
+
+func TestExpiredStaticTokenIsRejected(t *testing.T) {
+	client, err := NewWithToken("127.0.0.1:1", "token", time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	_, err = client.GetChatMessage(context.Background(), "message", "thread")
+	assert.True(t, errors.Is(err, ERR_EXPIRED_TOKEN))
 }
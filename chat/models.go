@@ -1,29 +1,42 @@
 package chat
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
 
-	"github.com/karim-w/go-azure-communication-services/identity"
+	"github.com/karim-w/go-azure-communication-services/client"
+	"github.com/karim-w/go-azure-communication-services/credential"
+	"github.com/karim-w/go-azure-communication-services/identifier"
 )
 
 type CreateChatThread struct {
-	Topic        string        `json:"topic"`
-	Participants []Participant `json:"participants"`
+	Topic                  string        `json:"topic"`
+	Participants           []Participant `json:"participants"`
+	RepeatabilityRequestID string        `json:"repeatabilityRequestId,omitempty"`
 }
 
-const _apiVersion = "2021-09-07"
+// _apiVersion is the chat API every call uses. It was 2021-09-07 until
+// thread metadata and retention policies, which need 2024-03-07.
+const _apiVersion = "2024-03-07"
 
 var (
-	ERR_UNAUTHORIZED      = fmt.Errorf("unauthorized")
-	ERR_EXPIRED_TOKEN     = fmt.Errorf("token expired")
-	ERR_NO_TOKEN_PROVIDED = fmt.Errorf("no token provided")
+	ERR_UNAUTHORIZED      = client.ERR_UNAUTHORIZED
+	ERR_EXPIRED_TOKEN     = credential.ERR_EXPIRED_TOKEN
+	ERR_NO_TOKEN_PROVIDED = credential.ERR_NO_TOKEN_PROVIDED
+	ERR_EMPTY_TOPIC       = errors.New("topic cannot be empty")
 )
 
 type Participant struct {
-	CommunicationIdentifier identity.CommunicationIdentifier `json:"communicationIdentifier"`
-	DisplayName             string                           `json:"displayName"`
+	CommunicationIdentifier identifier.Identifier `json:"communicationIdentifier"`
+	DisplayName             string                `json:"displayName"`
 }
 
+// ChatUser is a participant to add to a thread. ID is a raw id, so ACS
+// users, phone numbers and Teams users can all be added; see
+// identifier.Parse.
 type ChatUser struct {
 	ID          string `json:"id"`
 	DisplayName string `json:"displayName"`
@@ -35,20 +48,14 @@ type CreateChatThreadResponse struct {
 }
 
 type ChatThread struct {
-	ID                               string                           `json:"id"`
-	Topic                            string                           `json:"topic"`
-	CreatedOn                        string                           `json:"createdOn"`
-	CreatedByCommunicationIdentifier CreatedByCommunicationIdentifier `json:"createdByCommunicationIdentifier"`
+	ID                               string                `json:"id"`
+	Topic                            string                `json:"topic"`
+	CreatedOn                        string                `json:"createdOn"`
+	CreatedByCommunicationIdentifier identifier.Identifier `json:"createdByCommunicationIdentifier"`
 }
 
-type CreatedByCommunicationIdentifier struct {
-	RawID             string            `json:"rawId"`
-	CommunicationUser CommunicationUser `json:"communicationUser"`
-}
-
-type CommunicationUser struct {
-	ID string `json:"id"`
-}
+// Deprecated: use identifier.CommunicationUser.
+type CommunicationUser = identifier.CommunicationUser
 
 type InvalidParticipant struct {
 	Target  string `json:"target"`
@@ -83,24 +90,24 @@ type SendChatMessageResponse struct {
 }
 
 type ChatMessageContent struct {
-	InitiatorCommunicationIdentifier identity.CommunicationIdentifier `json:"initiatorCommunicationIdentifier"`
-	Message                          string                           `json:"message"`
-	Participants                     []Participant                    `json:"participants"`
-	Topic                            string                           `json:"topic"`
+	InitiatorCommunicationIdentifier identifier.Identifier `json:"initiatorCommunicationIdentifier"`
+	Message                          string                `json:"message"`
+	Participants                     []Participant         `json:"participants"`
+	Topic                            string                `json:"topic"`
 }
 
 type ChatMessage struct {
-	Content                       ChatMessageContent               `json:"content"`
-	CreatedOn                     string                           `json:"createdOn"`
-	DeletedOn                     string                           `json:"deletedOn"`
-	EditedOn                      string                           `json:"editedOn"`
-	ID                            string                           `json:"id"`
-	Metadata                      map[string]string                `json:"metadata"`
-	SenderCommunicationIdentifier identity.CommunicationIdentifier `json:"senderCommunicationIdentifier"`
-	SenderDisplayName             string                           `json:"senderDisplayName"`
-	SequenceId                    string                           `json:"sequenceId"`
-	Type                          ChatMessageType                  `json:"type"`
-	Version                       string                           `json:"version"`
+	Content                       ChatMessageContent    `json:"content"`
+	CreatedOn                     string                `json:"createdOn"`
+	DeletedOn                     string                `json:"deletedOn"`
+	EditedOn                      string                `json:"editedOn"`
+	ID                            string                `json:"id"`
+	Metadata                      map[string]string     `json:"metadata"`
+	SenderCommunicationIdentifier identifier.Identifier `json:"senderCommunicationIdentifier"`
+	SenderDisplayName             string                `json:"senderDisplayName"`
+	SequenceId                    string                `json:"sequenceId"`
+	Type                          ChatMessageType       `json:"type"`
+	Version                       string                `json:"version"`
 }
 
 type ListChatMessagesOptions struct {
@@ -109,6 +116,17 @@ type ListChatMessagesOptions struct {
 	StartTime    string `json:"startTime"`
 }
 
+func (o *ListChatMessagesOptions) query() string {
+	q := url.Values{"api-version": {_apiVersion}}
+	if o.MaxPageSize > 0 {
+		q.Set("maxPageSize", strconv.Itoa(o.MaxPageSize))
+	}
+	if o.StartTime != "" {
+		q.Set("startTime", o.StartTime)
+	}
+	return q.Encode()
+}
+
 type ChatMessagesCollection struct {
 	NextLink string        `json:"nextLink"`
 	Value    []ChatMessage `json:"value"`
@@ -124,6 +142,17 @@ type ListChatThreadsOptions struct {
 	StartTime   string `json:"startTime"`
 }
 
+func (o *ListChatThreadsOptions) query() string {
+	q := url.Values{"api-version": {_apiVersion}}
+	if o.MaxPageSize > 0 {
+		q.Set("maxPageSize", strconv.Itoa(o.MaxPageSize))
+	}
+	if o.StartTime != "" {
+		q.Set("startTime", o.StartTime)
+	}
+	return q.Encode()
+}
+
 type ChatThreadsItem struct {
 	DeletedOn             string `json:"deletedOn"`
 	ID                    string `json:"id"`
@@ -142,13 +171,122 @@ type ListChatParticipantsOptions struct {
 	Skip         int    `json:"skip"`
 }
 
+func (o *ListChatParticipantsOptions) query() string {
+	q := url.Values{"api-version": {_apiVersion}}
+	if o.MaxPageSize > 0 {
+		q.Set("maxPageSize", strconv.Itoa(o.MaxPageSize))
+	}
+	if o.Skip > 0 {
+		q.Set("skip", strconv.Itoa(o.Skip))
+	}
+	return q.Encode()
+}
+
 type ChatParticipant struct {
-	CommunicationIdentifier identity.CommunicationIdentifier `json:"communicationIdentifier"`
-	DisplayName             string                           `json:"displayName"`
-	ShareHistoryTime        string                           `json:"shareHistoryTime"`
+	CommunicationIdentifier identifier.Identifier `json:"communicationIdentifier"`
+	DisplayName             string                `json:"displayName"`
+	ShareHistoryTime        string                `json:"shareHistoryTime"`
 }
 
 type ChatParticipantsCollection struct {
 	NextLink string            `json:"nextLink"`
 	Value    []ChatParticipant `json:"value"`
 }
+
+type sendReadReceiptRequest struct {
+	ChatMessageID string `json:"chatMessageId"`
+}
+
+type ListReadReceiptsOptions struct {
+	MaxPageSize int `json:"maxPageSize"`
+	Skip        int `json:"skip"`
+}
+
+func (o *ListReadReceiptsOptions) query() string {
+	q := url.Values{"api-version": {_apiVersion}}
+	if o == nil {
+		return q.Encode()
+	}
+	if o.MaxPageSize > 0 {
+		q.Set("maxPageSize", strconv.Itoa(o.MaxPageSize))
+	}
+	if o.Skip > 0 {
+		q.Set("skip", strconv.Itoa(o.Skip))
+	}
+	return q.Encode()
+}
+
+// ChatMessageReadReceipt records the latest message a participant has read.
+type ChatMessageReadReceipt struct {
+	SenderCommunicationIdentifier identifier.Identifier `json:"senderCommunicationIdentifier"`
+	ChatMessageID                 string                `json:"chatMessageId"`
+	ReadOn                        time.Time             `json:"readOn"`
+}
+
+type ChatMessageReadReceiptsCollection struct {
+	NextLink string                   `json:"nextLink"`
+	Value    []ChatMessageReadReceipt `json:"value"`
+}
+
+// DefaultTypingNotificationWindow is how often a chat client sends typing
+// notifications to a thread at most, matching how long clients show them.
+const DefaultTypingNotificationWindow = 8 * time.Second
+
+type SendTypingNotificationOptions struct {
+	SenderDisplayName string `json:"senderDisplayName"`
+}
+
+type sendTypingNotificationRequest struct {
+	SenderDisplayName string `json:"senderDisplayName,omitempty"`
+}
+
+type RetentionPolicyKind string
+
+const (
+	RetentionPolicyKind_ThreadCreationDate RetentionPolicyKind = "threadCreationDate"
+	RetentionPolicyKind_None               RetentionPolicyKind = "none"
+)
+
+// ChatRetentionPolicy controls when the service deletes a thread.
+type ChatRetentionPolicy struct {
+	Kind                  RetentionPolicyKind `json:"kind"`
+	DeleteThreadAfterDays int                 `json:"deleteThreadAfterDays,omitempty"`
+}
+
+type ChatThreadProperties struct {
+	ID                               string                `json:"id"`
+	Topic                            string                `json:"topic"`
+	CreatedOn                        time.Time             `json:"createdOn"`
+	CreatedByCommunicationIdentifier identifier.Identifier `json:"createdByCommunicationIdentifier"`
+	DeletedOn                        time.Time             `json:"deletedOn"`
+	Metadata                         map[string]string     `json:"metadata"`
+	RetentionPolicy                  *ChatRetentionPolicy  `json:"retentionPolicy"`
+}
+
+// UpdateChatThreadOptions is sent as a JSON merge patch: only the fields
+// that are set change.
+type UpdateChatThreadOptions struct {
+	Topic string
+	// Metadata replaces the thread metadata when non-nil.
+	Metadata map[string]string
+	// RetentionPolicy replaces the retention policy when non-nil.
+	RetentionPolicy *ChatRetentionPolicy
+	// RemoveRetentionPolicy clears the retention policy.
+	RemoveRetentionPolicy bool
+}
+
+func (o UpdateChatThreadOptions) MarshalJSON() ([]byte, error) {
+	patch := map[string]interface{}{}
+	if o.Topic != "" {
+		patch["topic"] = o.Topic
+	}
+	if o.Metadata != nil {
+		patch["metadata"] = o.Metadata
+	}
+	if o.RetentionPolicy != nil {
+		patch["retentionPolicy"] = o.RetentionPolicy
+	} else if o.RemoveRetentionPolicy {
+		patch["retentionPolicy"] = nil
+	}
+	return json.Marshal(patch)
+}
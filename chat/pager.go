@@ -0,0 +1,146 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+// Pager walks a paged chat collection. Every page after the first one is
+// fetched from the nextLink returned by the service, using the same host
+// and token as the client that created the pager.
+type Pager[T any] struct {
+	c    *_chat
+	link client.PageLink
+}
+
+type page[T any] struct {
+	NextLink string `json:"nextLink"`
+	Value    []T    `json:"value"`
+}
+
+func newPager[T any](
+	c *_chat,
+	resource string,
+	query string,
+	maxPageSize int,
+) *Pager[T] {
+	defaults := url.Values{"api-version": {_apiVersion}}
+	if maxPageSize > 0 {
+		defaults.Set("maxPageSize", strconv.Itoa(maxPageSize))
+	}
+	return &Pager[T]{
+		c: c,
+		link: client.PageLink{
+			Resource: resource,
+			Query:    query,
+			Defaults: defaults,
+		},
+	}
+}
+
+// More reports whether another page can be fetched.
+func (p *Pager[T]) More() bool {
+	return !p.link.Done
+}
+
+// NextPage fetches the next page. It returns an empty page once the
+// collection is exhausted.
+func (p *Pager[T]) NextPage(ctx context.Context) ([]T, error) {
+	if p.link.Done {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response := page[T]{}
+	err := p.c.send(
+		ctx,
+		http.MethodGet,
+		p.link.Resource,
+		p.link.Query,
+		"application/json",
+		nil,
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.link.Follow(p.c.host, response.NextLink); err != nil {
+		return nil, err
+	}
+	return response.Value, nil
+}
+
+// All fetches every remaining page and returns the concatenated items.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	items := []T{}
+	for p.More() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page...)
+	}
+	return items, nil
+}
+
+func (c *_chat) NewListChatThreadsPager(
+	opts *ListChatThreadsOptions,
+) *Pager[ChatThreadsItem] {
+	if opts == nil {
+		opts = &ListChatThreadsOptions{}
+	}
+	return newPager[ChatThreadsItem](
+		c,
+		"/chat/threads",
+		opts.query(),
+		opts.MaxPageSize,
+	)
+}
+
+func (c *_chat) NewListChatMessagesPager(
+	opts *ListChatMessagesOptions,
+) *Pager[ChatMessage] {
+	if opts == nil {
+		opts = &ListChatMessagesOptions{}
+	}
+	return newPager[ChatMessage](
+		c,
+		"/chat/threads/"+url.PathEscape(opts.ChatThreadId)+"/messages",
+		opts.query(),
+		opts.MaxPageSize,
+	)
+}
+
+func (c *_chat) NewListChatParticipantsPager(
+	opts *ListChatParticipantsOptions,
+) *Pager[ChatParticipant] {
+	if opts == nil {
+		opts = &ListChatParticipantsOptions{}
+	}
+	return newPager[ChatParticipant](
+		c,
+		"/chat/threads/"+url.PathEscape(opts.ChatThreadId)+"/participants",
+		opts.query(),
+		opts.MaxPageSize,
+	)
+}
+
+func (c *_chat) NewListReadReceiptsPager(
+	threadID string,
+	opts *ListReadReceiptsOptions,
+) *Pager[ChatMessageReadReceipt] {
+	if opts == nil {
+		opts = &ListReadReceiptsOptions{}
+	}
+	return newPager[ChatMessageReadReceipt](
+		c,
+		"/chat/threads/"+url.PathEscape(threadID)+"/readReceipts",
+		opts.query(),
+		opts.MaxPageSize,
+	)
+}
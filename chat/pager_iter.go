@@ -0,0 +1,17 @@
+//go:build go1.23
+
+package chat
+
+import (
+	"context"
+	"iter"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+// Items returns an iterator over every remaining item, fetching pages
+// lazily. Iteration stops after the first error, which is yielded with the
+// zero value of T.
+func (p *Pager[T]) Items(ctx context.Context) iter.Seq2[T, error] {
+	return client.PageItems[T](ctx, p)
+}
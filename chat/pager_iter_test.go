@@ -0,0 +1,25 @@
+//go:build go1.23
+
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerItems(t *testing.T) {
+	client, _ := newPagedServer(t)
+	ids := []string{}
+	for m, err := range client.NewListChatMessagesPager(&ListChatMessagesOptions{
+		ChatThreadId: "thread",
+	}).Items(context.Background()) {
+		assert.Nil(t, err)
+		ids = append(ids, m.ID)
+		if len(ids) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karim-w/go-azure-communication-services/acstest"
+	acsclient "github.com/karim-w/go-azure-communication-services/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPagedServer(t *testing.T) (Chat, *[]string) {
+	var srv *httptest.Server
+	queries := []string{}
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/chat/threads/thread/messages", r.URL.Path)
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("syncState") {
+		case "":
+			w.Write([]byte(`{"value":[{"id":"1"},{"id":"2"}],"nextLink":"` + srv.URL + `/chat/threads/thread/messages?syncState=page2&api-version=2021-09-07"}`))
+		case "page2":
+			w.Write([]byte(`{"value":[{"id":"3"}],"nextLink":"https://elsewhere.example/chat/threads/thread/messages?syncState=page3"}`))
+		default:
+			w.Write([]byte(`{"value":[{"id":"4"}]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client, err := NewWithToken(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"token",
+		time.Now().Add(time.Hour),
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	assert.Nil(t, err)
+	return client, &queries
+}
+
+func TestPagerFollowsNextLink(t *testing.T) {
+	client, queries := newPagedServer(t)
+	pager := client.NewListChatMessagesPager(&ListChatMessagesOptions{
+		ChatThreadId: "thread",
+		MaxPageSize:  2,
+	})
+	pages := 0
+	ids := []string{}
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		assert.Nil(t, err)
+		for _, m := range page {
+			ids = append(ids, m.ID)
+		}
+		pages++
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids)
+	for _, q := range *queries {
+		assert.Contains(t, q, "maxPageSize=2")
+		assert.Contains(t, q, "api-version=")
+	}
+}
+
+func TestPagerAll(t *testing.T) {
+	client, _ := newPagedServer(t)
+	items, err := client.NewListChatMessagesPager(&ListChatMessagesOptions{
+		ChatThreadId: "thread",
+	}).All(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, items, 4)
+}
+
+func TestPagerHonorsCanceledContext(t *testing.T) {
+	client, queries := newPagedServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pager := client.NewListChatMessagesPager(&ListChatMessagesOptions{
+		ChatThreadId: "thread",
+	})
+	_, err := pager.NextPage(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, pager.More())
+	assert.Empty(t, *queries)
+}
+
+func TestPagerUnderEndpointBasePath(t *testing.T) {
+	srv := acstest.NewServerWithOptions(&acstest.ServerOptions{
+		PlainHTTP: true,
+		BasePath:  "/acs",
+	})
+	defer srv.Close()
+	client, err := NewWithToken(
+		srv.Endpoint(),
+		srv.IssueToken(srv.CreateIdentity(), []string{"chat"}, time.Hour),
+		time.Now().Add(time.Hour),
+		srv.ClientOptions()...,
+	)
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := client.CreateChatThread(context.Background(), "topic")
+		assert.Nil(t, err)
+	}
+	threads, err := client.NewListChatThreadsPager(&ListChatThreadsOptions{
+		MaxPageSize: 2,
+	}).All(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, threads, 3)
+}
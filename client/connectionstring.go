@@ -0,0 +1,68 @@
+package client
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ERR_INVALID_CONNECTION_STRING = errors.New("invalid connection string")
+	ERR_MISSING_ENDPOINT          = errors.New("connection string is missing the endpoint")
+	ERR_MISSING_ACCESS_KEY        = errors.New("connection string is missing the access key")
+)
+
+// ConnectionString is the parsed form of an ACS connection string such as
+// "endpoint=https://x.communication.azure.com/;accesskey=...".
+type ConnectionString struct {
+	// Endpoint is the endpoint exactly as it appeared in the connection string.
+	Endpoint string
+	// AccessKey is the base64 encoded access key.
+	AccessKey string
+}
+
+// ParseConnectionString parses an ACS connection string. Keys are matched
+// case-insensitively and the endpoint must be an absolute http(s) URL.
+func ParseConnectionString(connectionString string) (*ConnectionString, error) {
+	var endpoint, accessKey string
+	for _, segment := range strings.Split(connectionString, ";") {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+		// access keys are base64 encoded and may end in '=', so only
+		// the first separator splits the key from the value.
+		k, v, ok := strings.Cut(segment, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed segment %q", ERR_INVALID_CONNECTION_STRING, segment)
+		}
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "endpoint":
+			endpoint = strings.TrimSpace(v)
+		case "accesskey":
+			accessKey = strings.TrimSpace(v)
+		}
+	}
+	if endpoint == "" {
+		return nil, ERR_MISSING_ENDPOINT
+	}
+	if accessKey == "" {
+		return nil, ERR_MISSING_ACCESS_KEY
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ERR_INVALID_CONNECTION_STRING, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%w: endpoint %q is not an absolute http(s) URL", ERR_INVALID_CONNECTION_STRING, endpoint)
+	}
+	if _, err := base64.StdEncoding.DecodeString(accessKey); err != nil {
+		return nil, fmt.Errorf("%w: access key is not valid base64", ERR_INVALID_CONNECTION_STRING)
+	}
+	return &ConnectionString{
+		Endpoint:  endpoint,
+		AccessKey: accessKey,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConnectionString(t *testing.T) {
+	cs, err := ParseConnectionString("endpoint=https://x.communication.azure.com/;accesskey=c2VjcmV0a2V5PT0=")
+	assert.Nil(t, err)
+	assert.Equal(t, "c2VjcmV0a2V5PT0=", cs.AccessKey)
+	assert.Equal(t, "https://x.communication.azure.com/", cs.Endpoint)
+}
+
+func TestParseConnectionStringWithPortAndCustomDomain(t *testing.T) {
+	cs, err := ParseConnectionString("AccessKey=c2VjcmV0;Endpoint=https://acs.contoso.com:8443/;")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://acs.contoso.com:8443/", cs.Endpoint)
+	assert.Equal(t, "c2VjcmV0", cs.AccessKey)
+}
+
+func TestParseConnectionStringErrors(t *testing.T) {
+	cases := map[string]error{
+		"":                                    ERR_MISSING_ENDPOINT,
+		"accesskey=c2VjcmV0":                  ERR_MISSING_ENDPOINT,
+		"endpoint=https://x.com/":             ERR_MISSING_ACCESS_KEY,
+		"endpoint=https://x.com/;accesskey":   ERR_INVALID_CONNECTION_STRING,
+		"endpoint=x.com;accesskey=c2VjcmV0":   ERR_INVALID_CONNECTION_STRING,
+		"endpoint=ftp://x.com;accesskey=c2Vj": ERR_INVALID_CONNECTION_STRING,
+		"endpoint=https://x.com;accesskey=%%": ERR_INVALID_CONNECTION_STRING,
+	}
+	for cs, want := range cases {
+		_, err := ParseConnectionString(cs)
+		assert.True(t, errors.Is(err, want), "%q: got %v, want %v", cs, err, want)
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Credential authorizes the requests sent by a Client. Authorize is called
+// before every attempt, including retries, and adds its headers to header.
+type Credential interface {
+	Authorize(
+		ctx context.Context,
+		method string,
+		host string,
+		resource string,
+		body []byte,
+		header http.Header,
+	) error
+}
+
+type keyCredential struct {
+	key string
+}
+
+// NewKeyCredential signs requests with an ACS access key using
+// HMAC-SHA256.
+func NewKeyCredential(key string) Credential {
+	return &keyCredential{key}
+}
+
+func (k *keyCredential) Authorize(
+	ctx context.Context,
+	method string,
+	host string,
+	resource string,
+	body []byte,
+	header http.Header,
+) error {
+	date := time.Now().UTC().Format(http.TimeFormat)
+	contentHash, authHeader := createAuthHeader(
+		method,
+		host,
+		resource,
+		date,
+		k.key,
+		body,
+	)
+	header.Set("X-Ms-Date", date)
+	header.Set("X-Ms-Content-Sha256", contentHash)
+	header.Set("Authorization", authHeader)
+	return nil
+}
+
+type bearerTokenCredential struct {
+	getToken func(ctx context.Context) (string, error)
+}
+
+// NewBearerTokenCredential authorizes requests with the bearer token
+// returned by getToken, which is called before every attempt.
+func NewBearerTokenCredential(
+	getToken func(ctx context.Context) (string, error),
+) Credential {
+	return &bearerTokenCredential{getToken}
+}
+
+func (b *bearerTokenCredential) Authorize(
+	ctx context.Context,
+	method string,
+	host string,
+	resource string,
+	body []byte,
+	header http.Header,
+) error {
+	token, err := b.getToken(ctx)
+	if err != nil {
+		return err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return nil
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var ERR_INVALID_ENDPOINT = errors.New("invalid endpoint")
+
+// ParseEndpoint resolves the endpoint passed to the service constructors.
+// It is either a bare host such as "x.communication.azure.com" or
+// "localhost:8080", which is reached over https, or a full URL with scheme,
+// host, port and base path such as "http://localhost:8080/acs/". The
+// returned URL has no trailing slash so resource paths can be appended.
+func ParseEndpoint(endpoint string) (*url.URL, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: endpoint is empty", ERR_INVALID_ENDPOINT)
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ERR_INVALID_ENDPOINT, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q is not an absolute http(s) URL", ERR_INVALID_ENDPOINT, endpoint)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("%w: %q must not have credentials, a query or a fragment", ERR_INVALID_ENDPOINT, endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	return u, nil
+}
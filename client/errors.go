@@ -0,0 +1,111 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ERR_UNAUTHORIZED matches any *ResponseError carrying a 401 status, so
+// callers can keep using errors.Is for the common case.
+var ERR_UNAUTHORIZED = errors.New("unauthorized")
+
+// ErrorDetail is a single entry of the ACS error envelope.
+type ErrorDetail struct {
+	Code       string        `json:"code"`
+	Message    string        `json:"message"`
+	Target     string        `json:"target,omitempty"`
+	Details    []ErrorDetail `json:"details,omitempty"`
+	InnerError *InnerError   `json:"innererror,omitempty"`
+}
+
+// InnerError carries the more specific, nested error codes reported by ACS.
+type InnerError struct {
+	Code       string      `json:"code"`
+	InnerError *InnerError `json:"innererror,omitempty"`
+}
+
+type errorEnvelope struct {
+	Error *ErrorDetail `json:"error"`
+}
+
+// ResponseError is returned whenever ACS answers with a non-success status.
+// It is meant to be inspected with errors.As.
+type ResponseError struct {
+	// StatusCode is the HTTP status returned by the service.
+	StatusCode int
+	// ErrorCode is the ACS error code, or the status text without spaces
+	// (e.g. "NotFound", "TooManyRequests") when the service sent none.
+	ErrorCode  string
+	Message    string
+	Target     string
+	Details    []ErrorDetail
+	InnerError *InnerError
+	// RequestID, ClientRequestID and CorrelationVector echo the tracing
+	// headers of the failed response, useful when opening support cases.
+	RequestID         string
+	ClientRequestID   string
+	CorrelationVector string
+	// RawBody is the unparsed response body.
+	RawBody []byte
+}
+
+func newResponseError(statusCode int, header http.Header, body []byte) *ResponseError {
+	e := &ResponseError{
+		StatusCode:        statusCode,
+		RequestID:         header.Get("X-Ms-Request-Id"),
+		ClientRequestID:   header.Get("X-Ms-Client-Request-Id"),
+		CorrelationVector: header.Get("Ms-Cv"),
+		RawBody:           body,
+	}
+	var envelope errorEnvelope
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error != nil {
+		e.ErrorCode = envelope.Error.Code
+		e.Message = envelope.Error.Message
+		e.Target = envelope.Error.Target
+		e.Details = envelope.Error.Details
+		e.InnerError = envelope.Error.InnerError
+	} else if len(body) > 0 {
+		e.Message = string(body)
+	}
+	if e.ErrorCode == "" {
+		e.ErrorCode = strings.ReplaceAll(http.StatusText(statusCode), " ", "")
+	}
+	return e
+}
+
+func (e *ResponseError) Error() string {
+	msg := fmt.Sprintf("acs: %d %s", e.StatusCode, e.ErrorCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Target != "" {
+		msg += " (target: " + e.Target + ")"
+	}
+	return msg
+}
+
+// Is reports a 401 response as ERR_UNAUTHORIZED.
+func (e *ResponseError) Is(target error) bool {
+	return target == ERR_UNAUTHORIZED && e.StatusCode == http.StatusUnauthorized
+}
+
+// HasErrorCode reports whether err is a *ResponseError whose ErrorCode or
+// any nested inner error code equals code.
+func HasErrorCode(err error, code string) bool {
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		return false
+	}
+	if re.ErrorCode == code {
+		return true
+	}
+	for inner := re.InnerError; inner != nil; inner = inner.InnerError {
+		if inner.Code == code {
+			return true
+		}
+	}
+	return false
+}
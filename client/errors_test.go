@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseErrorFromEnvelope(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ms-Request-Id", "req-1")
+		w.Header().Set("MS-CV", "cv-1")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":"Forbidden","message":"nope","target":"roomId","details":[{"code":"Detail","message":"d"}],"innererror":{"code":"Outer","innererror":{"code":"Inner"}}}}`))
+	})
+	err := c.Get(context.Background(), host, "/rooms/x", "api-version=1", nil)
+	var re *ResponseError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, http.StatusForbidden, re.StatusCode)
+	assert.Equal(t, "Forbidden", re.ErrorCode)
+	assert.Equal(t, "nope", re.Message)
+	assert.Equal(t, "roomId", re.Target)
+	assert.Len(t, re.Details, 1)
+	assert.Equal(t, "req-1", re.RequestID)
+	assert.Equal(t, "cv-1", re.CorrelationVector)
+	assert.True(t, HasErrorCode(err, "Inner"))
+	assert.False(t, HasErrorCode(err, "NotFound"))
+	assert.False(t, errors.Is(err, ERR_UNAUTHORIZED))
+}
+
+func TestResponseErrorWithoutEnvelope(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("slow down"))
+	})
+	err := c.Delete(context.Background(), host, "/rooms/x", "api-version=1", nil)
+	assert.True(t, HasErrorCode(err, "TooManyRequests"))
+	assert.Equal(t, "acs: 429 TooManyRequests: slow down", err.Error())
+}
+
+func TestResponseErrorUnauthorized(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	err := c.Get(context.Background(), host, "/chat/threads", "api-version=1", nil)
+	assert.True(t, errors.Is(err, ERR_UNAUTHORIZED))
+	assert.True(t, HasErrorCode(err, "Unauthorized"))
+}
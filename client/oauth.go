@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/karim-w/go-azure-communication-services/credential"
+)
+
+const (
+	defaultAuthorityHost = "https://login.microsoftonline.com"
+	defaultScope         = "https://communication.azure.com/.default"
+	// tokenRefreshWindow is how long before expiry a cached token is
+	// replaced.
+	tokenRefreshWindow = 5 * time.Minute
+)
+
+// ClientSecretCredentialOptions tunes NewClientSecretCredential.
+type ClientSecretCredentialOptions struct {
+	// TokenEndpoint overrides the Microsoft Entra ID token endpoint, which
+	// defaults to https://login.microsoftonline.com/{tenant}/oauth2/v2.0/token.
+	TokenEndpoint string
+	// Scope defaults to https://communication.azure.com/.default.
+	Scope string
+	// HTTPClient is used to call the token endpoint.
+	HTTPClient *http.Client
+}
+
+type clientSecretCredential struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	scope        string
+	httpClient   *http.Client
+
+	// tokens caches the Entra ID token and renews it shortly before it
+	// expires, one request at a time.
+	tokens credential.CommunicationTokenCredential
+}
+
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	TokenType   string      `json:"token_type"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+// NewClientSecretCredential authenticates as a Microsoft Entra ID
+// application through the OAuth2 client credentials flow. Tokens are
+// cached and renewed shortly before they expire.
+func NewClientSecretCredential(
+	tenantID string,
+	clientID string,
+	clientSecret string,
+	opts *ClientSecretCredentialOptions,
+) Credential {
+	c := &clientSecretCredential{
+		endpoint:     defaultAuthorityHost + "/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token",
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scope:        defaultScope,
+		httpClient:   &http.Client{},
+	}
+	if opts != nil {
+		if opts.TokenEndpoint != "" {
+			c.endpoint = opts.TokenEndpoint
+		}
+		if opts.Scope != "" {
+			c.scope = opts.Scope
+		}
+		if opts.HTTPClient != nil {
+			c.httpClient = opts.HTTPClient
+		}
+	}
+	c.tokens = credential.NewRefreshingTokenCredential(
+		c.requestToken,
+		&credential.RefreshOptions{RefreshWindow: tokenRefreshWindow},
+	)
+	return c
+}
+
+func (c *clientSecretCredential) Authorize(
+	ctx context.Context,
+	method string,
+	host string,
+	resource string,
+	body []byte,
+	header http.Header,
+) error {
+	token, err := c.tokens.GetToken(ctx)
+	if err != nil {
+		return err
+	}
+	header.Set("Authorization", "Bearer "+token.Token)
+	return nil
+}
+
+// requestToken fetches a new token from the token endpoint.
+func (c *clientSecretCredential) requestToken(ctx context.Context) (credential.AccessToken, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+		"scope":         {c.scope},
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.endpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return credential.AccessToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return credential.AccessToken{}, err
+	}
+	defer res.Body.Close()
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return credential.AccessToken{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return credential.AccessToken{}, fmt.Errorf("token request failed with status %d: %s", res.StatusCode, responseBody)
+	}
+	var token tokenResponse
+	if err := json.Unmarshal(responseBody, &token); err != nil {
+		return credential.AccessToken{}, err
+	}
+	if token.AccessToken == "" {
+		return credential.AccessToken{}, fmt.Errorf("token response did not contain an access token")
+	}
+	expiresIn, _ := token.ExpiresIn.Int64()
+	return credential.AccessToken{
+		Token:     token.AccessToken,
+		ExpiresOn: time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeTokenEndpoint(t *testing.T, expiresIn string) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "app", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		assert.Equal(t, "https://communication.azure.com/.default", r.PostForm.Get("scope"))
+		w.Write([]byte(`{"token_type":"Bearer","expires_in":` + expiresIn + `,"access_token":"aad-token"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestClientSecretCredentialCachesToken(t *testing.T) {
+	tokenSrv, calls := newFakeTokenEndpoint(t, "3600")
+	cred := NewClientSecretCredential("tenant", "app", "secret", &ClientSecretCredentialOptions{
+		TokenEndpoint: tokenSrv.URL,
+	})
+	var auth []string
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("x-ms-content-sha256"))
+	})
+	c.cred = cred
+	assert.Nil(t, c.Get(context.Background(), host, "/rooms/x", "api-version=1", nil))
+	assert.Nil(t, c.Delete(context.Background(), host, "/rooms/x", "api-version=1", nil))
+	assert.Equal(t, []string{"Bearer aad-token", "Bearer aad-token"}, auth)
+	assert.Equal(t, int32(1), *calls)
+}
+
+func TestClientSecretCredentialRenewsNearExpiry(t *testing.T) {
+	tokenSrv, calls := newFakeTokenEndpoint(t, "60")
+	cred := NewClientSecretCredential("tenant", "app", "secret", &ClientSecretCredentialOptions{
+		TokenEndpoint: tokenSrv.URL,
+	})
+	h := http.Header{}
+	assert.Nil(t, cred.Authorize(context.Background(), "GET", "host", "/", nil, h))
+	assert.Nil(t, cred.Authorize(context.Background(), "GET", "host", "/", nil, h))
+	assert.Equal(t, int32(2), *calls)
+}
+
+func TestClientSecretCredentialError(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer tokenSrv.Close()
+	cred := NewClientSecretCredential("tenant", "app", "bad", &ClientSecretCredentialOptions{
+		TokenEndpoint: tokenSrv.URL,
+	})
+	called := false
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	c.cred = cred
+	err := c.Get(context.Background(), host, "/rooms/x", "api-version=1", nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+	assert.False(t, called)
+}
+
+func TestClientSecretCredentialServesValidTokenDuringRefresh(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-release
+		}
+		w.Write([]byte(`{"token_type":"Bearer","expires_in":240,"access_token":"aad-token"}`))
+	}))
+	defer tokenSrv.Close()
+	defer close(release)
+	cred := NewClientSecretCredential("tenant", "app", "secret", &ClientSecretCredentialOptions{
+		TokenEndpoint: tokenSrv.URL,
+	})
+	assert.Nil(t, cred.Authorize(context.Background(), "GET", "host", "/", nil, http.Header{}))
+	// the token is inside the refresh window, so this starts a refresh
+	// that blocks on the token endpoint.
+	go cred.Authorize(context.Background(), "GET", "host", "/", nil, http.Header{})
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, time.Millisecond)
+	h := http.Header{}
+	assert.Nil(t, cred.Authorize(context.Background(), "GET", "host", "/", nil, h))
+	assert.Equal(t, "Bearer aad-token", h.Get("Authorization"))
+}
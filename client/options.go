@@ -0,0 +1,26 @@
+package client
+
+import "net/http"
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends every request through httpClient, which allows
+// proxies, custom TLS settings, connection pool limits or test doubles.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.transport.httpClient = httpClient
+		}
+	}
+}
+
+// WithTransport sends every request through roundTripper using an
+// otherwise default http.Client.
+func WithTransport(roundTripper http.RoundTripper) Option {
+	return func(c *Client) {
+		if roundTripper != nil {
+			c.transport.httpClient = &http.Client{Transport: roundTripper}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PageLink is the resource and query of the next page of a paged
+// collection.
+type PageLink struct {
+	Resource string
+	Query    string
+	// Defaults are query parameters, such as api-version or maxPageSize,
+	// added back to a nextLink that lacks them.
+	Defaults url.Values
+	// Done is set once the service returns no nextLink.
+	Done bool
+}
+
+// Follow points the link at nextLink, or marks it done when nextLink is
+// empty. Only the path and query of nextLink are used so credentials are
+// never sent to another host, and a path that includes the base path of
+// endpoint is made relative to it again.
+func (l *PageLink) Follow(endpoint string, nextLink string) error {
+	if nextLink == "" {
+		l.Done = true
+		return nil
+	}
+	u, err := url.Parse(nextLink)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	for key, values := range l.Defaults {
+		if q.Get(key) == "" && len(values) > 0 && values[0] != "" {
+			q[key] = values
+		}
+	}
+	resource := u.EscapedPath()
+	if base, err := ParseEndpoint(endpoint); err == nil && base.Path != "" {
+		if trimmed := strings.TrimPrefix(resource, base.EscapedPath()); strings.HasPrefix(trimmed, "/") {
+			resource = trimmed
+		}
+	}
+	l.Resource = resource
+	l.Query = q.Encode()
+	return nil
+}
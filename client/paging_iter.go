@@ -0,0 +1,35 @@
+//go:build go1.23
+
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// Pages is a paged collection walked with More and NextPage.
+type Pages[T any] interface {
+	More() bool
+	NextPage(ctx context.Context) ([]T, error)
+}
+
+// PageItems returns an iterator over every remaining item of pages,
+// fetching pages lazily. Iteration stops after the first error, which is
+// yielded with the zero value of T.
+func PageItems[T any](ctx context.Context, pages Pages[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for pages.More() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
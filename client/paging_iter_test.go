@@ -0,0 +1,42 @@
+//go:build go1.23
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPages struct {
+	pages [][]int
+	err   error
+}
+
+func (p *testPages) More() bool { return len(p.pages) > 0 || p.err != nil }
+
+func (p *testPages) NextPage(ctx context.Context) ([]int, error) {
+	if len(p.pages) == 0 {
+		return nil, p.err
+	}
+	page := p.pages[0]
+	p.pages = p.pages[1:]
+	return page, nil
+}
+
+func TestPageItems(t *testing.T) {
+	errFailed := errors.New("failed")
+	items := []int{}
+	var got error
+	for item, err := range PageItems[int](context.Background(), &testPages{[][]int{{1, 2}, {3}}, errFailed}) {
+		if err != nil {
+			got = err
+			break
+		}
+		items = append(items, item)
+	}
+	assert.Equal(t, []int{1, 2, 3}, items)
+	assert.Equal(t, errFailed, got)
+}
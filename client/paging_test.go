@@ -0,0 +1,36 @@
+package client
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageLinkFollow(t *testing.T) {
+	link := &PageLink{
+		Defaults: url.Values{"api-version": {"2021-09-07"}, "maxPageSize": {"2"}},
+	}
+	assert.Nil(t, link.Follow("x.communication.azure.com", "https://elsewhere.example/chat/threads?skip=2"))
+	assert.Equal(t, "/chat/threads", link.Resource)
+	assert.Equal(t, "api-version=2021-09-07&maxPageSize=2&skip=2", link.Query)
+	assert.False(t, link.Done)
+	assert.Nil(t, link.Follow("x.communication.azure.com", ""))
+	assert.True(t, link.Done)
+}
+
+func TestPageLinkFollowKeepsLinkParameters(t *testing.T) {
+	link := &PageLink{
+		Defaults: url.Values{"api-version": {"2"}, "maxPageSize": {""}},
+	}
+	assert.Nil(t, link.Follow("x.communication.azure.com", "/chat/threads/a%2Fb/messages?api-version=1&maxPageSize=5"))
+	assert.Equal(t, "/chat/threads/a%2Fb/messages", link.Resource)
+	assert.Equal(t, "api-version=1&maxPageSize=5", link.Query)
+}
+
+func TestPageLinkFollowUnderEndpointBasePath(t *testing.T) {
+	link := &PageLink{}
+	assert.Nil(t, link.Follow("http://localhost:8080/acs/", "http://localhost:8080/acs/chat/threads?skip=2"))
+	assert.Equal(t, "/chat/threads", link.Resource)
+	assert.Equal(t, "skip=2", link.Query)
+}
@@ -0,0 +1,253 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const userAgent = "go-azure-communication-services"
+
+// Request describes a single call to an ACS endpoint.
+type Request struct {
+	Method string
+	// Host is the endpoint the request is sent to: a bare host, reached
+	// over https, or a full URL with scheme, port and base path. See
+	// ParseEndpoint.
+	Host string
+	// Path is the escaped resource path, e.g. "/rooms/{roomId}", relative
+	// to the endpoint's base path.
+	Path  string
+	Query string
+	// Header holds extra headers such as If-Match or
+	// Repeatability-Request-ID.
+	Header http.Header
+	// Body is marshaled to JSON. It is ignored when RawBody is set.
+	Body interface{}
+	// RawBody is sent as-is.
+	RawBody []byte
+	// Idempotent marks a POST or PATCH as safe to retry, for example
+	// because it carries a Repeatability-Request-ID.
+	Idempotent bool
+	// Repeatable adds Repeatability-Request-ID and Repeatability-First-Sent
+	// headers, generated once per call or taken from the context (see
+	// WithRepeatabilityRequestID), and makes the request safe to retry.
+	Repeatable bool
+}
+
+// Response is a successful ACS response with its body fully read.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Decode unmarshals the response body into out. An empty body or a nil
+// out is not an error.
+func (r *Response) Decode(out interface{}) error {
+	if out == nil || len(r.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Body, out)
+}
+
+// Next invokes the remaining stages of the pipeline.
+type Next func(req *http.Request) (*http.Response, error)
+
+// Policy is one stage of the request pipeline. It may inspect or modify
+// the request and the response, and must call next to continue.
+type Policy func(req *http.Request, next Next) (*http.Response, error)
+
+// Logger receives one line per attempt from WithLogger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithPerCallPolicy adds a policy that runs once per call, before retries.
+func WithPerCallPolicy(policy Policy) Option {
+	return func(c *Client) {
+		c.perCall = append(c.perCall, policy)
+	}
+}
+
+// WithPerRetryPolicy adds a policy that runs for every attempt, after the
+// request has been authorized.
+func WithPerRetryPolicy(policy Policy) Option {
+	return func(c *Client) {
+		c.perRetry = append(c.perRetry, policy)
+	}
+}
+
+// WithLogger logs the method, URL, status and duration of every attempt.
+// Headers, including Authorization, are never logged.
+func WithLogger(logger Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithApplicationID prefixes the User-Agent header with applicationID.
+func WithApplicationID(applicationID string) Option {
+	return func(c *Client) {
+		c.applicationID = applicationID
+	}
+}
+
+type retrySafeKey struct{}
+
+// Do sends req through the pipeline: telemetry, per-call policies, retry,
+// authorization, per-retry policies, logging and finally the transport.
+// Non-success responses are returned as *ResponseError.
+func (c *Client) Do(ctx context.Context, req *Request) (*Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	body := req.RawBody
+	if body == nil && req.Body != nil {
+		var err error
+		body, err = json.Marshal(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	endpoint, err := ParseEndpoint(req.Host)
+	if err != nil {
+		return nil, err
+	}
+	url := endpoint.Scheme + "://" + endpoint.Host + endpoint.EscapedPath() + req.Path
+	if req.Query != "" {
+		url += "?" + req.Query
+	}
+	if req.Idempotent || req.Repeatable {
+		ctx = context.WithValue(ctx, retrySafeKey{}, true)
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.Header {
+		httpReq.Header[http.CanonicalHeaderKey(k)] = v
+	}
+	if req.Repeatable {
+		setRepeatabilityHeaders(ctx, httpReq.Header)
+	}
+	res, err := c.run(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, newResponseError(res.StatusCode, res.Header, responseBody)
+	}
+	return &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       responseBody,
+	}, nil
+}
+
+func (c *Client) run(req *http.Request) (*http.Response, error) {
+	policies := []Policy{c.telemetry}
+	policies = append(policies, c.perCall...)
+	policies = append(policies, c.retry.policy(), c.authorize)
+	policies = append(policies, c.perRetry...)
+	if c.logger != nil {
+		policies = append(policies, c.log)
+	}
+	var next Next = c.transport.do
+	for i := len(policies) - 1; i >= 0; i-- {
+		policy, rest := policies[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return policy(req, rest)
+		}
+	}
+	return next(req)
+}
+
+func (c *Client) telemetry(req *http.Request, next Next) (*http.Response, error) {
+	ua := userAgent
+	if c.applicationID != "" {
+		ua = c.applicationID + " " + ua
+	}
+	req.Header.Set("User-Agent", ua)
+	if req.Header.Get("X-Ms-Client-Request-Id") == "" {
+		req.Header.Set("X-Ms-Client-Request-Id", newUUID())
+	}
+	return next(req)
+}
+
+// authorize applies the client credential. It runs for every attempt so
+// each retry carries a fresh x-ms-date and signature.
+func (c *Client) authorize(req *http.Request, next Next) (*http.Response, error) {
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := c.cred.Authorize(
+		req.Context(),
+		req.Method,
+		req.URL.Host,
+		req.URL.RequestURI(),
+		body,
+		req.Header,
+	)
+	if err != nil {
+		return nil, &nonRetriableError{err}
+	}
+	return next(req)
+}
+
+// nonRetriableError stops the retry policy from retrying err, e.g. an
+// expired token. The retry policy unwraps it before returning.
+type nonRetriableError struct {
+	err error
+}
+
+func (e *nonRetriableError) Error() string { return e.err.Error() }
+
+func (e *nonRetriableError) Unwrap() error { return e.err }
+
+func (c *Client) log(req *http.Request, next Next) (*http.Response, error) {
+	start := time.Now()
+	res, err := next(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		c.logger.Printf("acs: %s %s failed after %s: %v", req.Method, req.URL.Redacted(), elapsed, err)
+		return res, err
+	}
+	c.logger.Printf("acs: %s %s %d in %s", req.Method, req.URL.Redacted(), res.StatusCode, elapsed)
+	return res, err
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestGetSignsTheEmptyBodyItSends(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Empty(t, body)
+		assert.Equal(t, computeContentHash(nil), r.Header.Get("x-ms-content-sha256"))
+		assert.NotEmpty(t, r.Header.Get("x-ms-client-request-id"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("User-Agent"), "my-app "))
+	}, WithApplicationID("my-app"))
+	c.cred = NewKeyCredential("c2VjcmV0")
+	assert.Nil(t, c.Get(context.Background(), host, "/rooms/x", "api-version=1", nil))
+}
+
+func TestDoWithCustomHeadersAndRawBody(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, `"etag"`, r.Header.Get("If-Match"))
+		assert.Equal(t, "id-1", r.Header.Get("Repeatability-Request-ID"))
+		assert.Equal(t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"raw":true}`, string(body))
+		w.Header().Set("ETag", `"etag2"`)
+		w.Write([]byte(`{"ok":true}`))
+	})
+	res, err := c.Do(context.Background(), &Request{
+		Method: http.MethodPut,
+		Host:   host,
+		Path:   "/rooms/x",
+		Query:  "api-version=1",
+		Header: http.Header{
+			"If-Match":                 {`"etag"`},
+			"Repeatability-Request-ID": {"id-1"},
+			"Content-Type":             {"application/merge-patch+json"},
+		},
+		RawBody: []byte(`{"raw":true}`),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `"etag2"`, res.Header.Get("ETag"))
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	assert.Nil(t, res.Decode(&out))
+	assert.True(t, out.OK)
+}
+
+func TestHead(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path == "/rooms/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	assert.Nil(t, c.Head(context.Background(), host, "/rooms/x", "api-version=1"))
+	assert.True(t, HasErrorCode(c.Head(context.Background(), host, "/rooms/missing", "api-version=1"), "NotFound"))
+}
+
+func TestPolicyOrderAndLogging(t *testing.T) {
+	order := []string{}
+	logger := &recordingLogger{}
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	},
+		WithPerCallPolicy(func(req *http.Request, next Next) (*http.Response, error) {
+			order = append(order, "per-call")
+			return next(req)
+		}),
+		WithPerRetryPolicy(func(req *http.Request, next Next) (*http.Response, error) {
+			assert.NotEmpty(t, req.Header.Get("Authorization"))
+			order = append(order, "per-retry")
+			return next(req)
+		}),
+		WithLogger(logger),
+	)
+	assert.Nil(t, c.Delete(context.Background(), host, "/rooms/x", "api-version=1", nil))
+	assert.Equal(t, []string{"per-call", "per-retry"}, order)
+	assert.Len(t, logger.lines, 1)
+	assert.Contains(t, logger.lines[0], "DELETE https://"+host+"/rooms/x?api-version=1 204")
+	assert.NotContains(t, logger.lines[0], "HMAC")
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Headers ACS uses to detect replays of non-idempotent requests. A request
+// repeated with the same id within the service's window returns the
+// original response instead of executing again.
+const (
+	HeaderRepeatabilityRequestID = "Repeatability-Request-ID"
+	HeaderRepeatabilityFirstSent = "Repeatability-First-Sent"
+)
+
+type repeatabilityKey struct{}
+
+// WithRepeatabilityRequestID makes the repeatable operation called with the
+// returned context use id instead of a generated one, so a caller that
+// retries the whole operation, for example after a crash, is deduplicated
+// as well. Use a fresh id for every logical operation.
+func WithRepeatabilityRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, repeatabilityKey{}, id)
+}
+
+// RepeatabilityRequestID returns the id set by WithRepeatabilityRequestID,
+// or "" if there is none.
+func RepeatabilityRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(repeatabilityKey{}).(string)
+	return id
+}
+
+// NewRepeatabilityRequestID returns a new random id for
+// WithRepeatabilityRequestID.
+func NewRepeatabilityRequestID() string {
+	return newUUID()
+}
+
+// setRepeatabilityHeaders stamps a repeatable request once per call, before
+// the retry policy, so every attempt carries the same id and first-sent
+// time. Headers set explicitly on the Request win.
+func setRepeatabilityHeaders(ctx context.Context, header http.Header) {
+	if header.Get(HeaderRepeatabilityRequestID) == "" {
+		id := RepeatabilityRequestID(ctx)
+		if id == "" {
+			id = newUUID()
+		}
+		header.Set(HeaderRepeatabilityRequestID, id)
+	}
+	if header.Get(HeaderRepeatabilityFirstSent) == "" {
+		header.Set(HeaderRepeatabilityFirstSent, time.Now().UTC().Format(http.TimeFormat))
+	}
+}
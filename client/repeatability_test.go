@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatableRequestReusesHeadersAcrossRetries(t *testing.T) {
+	var calls int32
+	ids, firstSent := []string{}, []string{}
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(HeaderRepeatabilityRequestID))
+		firstSent = append(firstSent, r.Header.Get(HeaderRepeatabilityFirstSent))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}, fastRetries)
+	_, err := c.Do(context.Background(), &Request{
+		Method:     http.MethodPost,
+		Host:       host,
+		Path:       "/rooms",
+		Query:      "api-version=1",
+		Body:       struct{}{},
+		Repeatable: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), calls)
+	assert.NotEmpty(t, ids[0])
+	assert.Equal(t, ids[0], ids[1])
+	_, err = http.ParseTime(firstSent[0])
+	assert.Nil(t, err)
+	assert.Equal(t, firstSent[0], firstSent[1])
+}
+
+func TestRepeatabilityRequestIDFromContext(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "my-key", r.Header.Get(HeaderRepeatabilityRequestID))
+	})
+	ctx := WithRepeatabilityRequestID(context.Background(), "my-key")
+	_, err := c.Do(ctx, &Request{
+		Method:     http.MethodPost,
+		Host:       host,
+		Path:       "/rooms",
+		Query:      "api-version=1",
+		Repeatable: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "my-key", RepeatabilityRequestID(ctx))
+}
+
+func TestOnlyRepeatableRequestsGetRepeatabilityHeaders(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(HeaderRepeatabilityRequestID))
+		assert.Empty(t, r.Header.Get(HeaderRepeatabilityFirstSent))
+	})
+	ctx := WithRepeatabilityRequestID(context.Background(), "my-key")
+	assert.Nil(t, c.Post(ctx, host, "/rooms", "api-version=1", nil, nil))
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxAttempts   = 4
+	defaultRetryDelay    = 800 * time.Millisecond
+	defaultMaxRetryDelay = 60 * time.Second
+)
+
+var defaultRetryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryOptions configures how failed requests are retried. The zero value
+// retries idempotent requests up to three times with exponential backoff.
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts, including the first
+	// one. Zero uses the default of 4; 1 disables retries.
+	MaxAttempts int
+	// RetryDelay is the base delay, doubled after every attempt.
+	// Defaults to 800ms.
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the computed backoff. Defaults to 60s. Delays
+	// requested by the service through Retry-After are not capped.
+	MaxRetryDelay time.Duration
+	// DisableJitter turns off the +/-20% randomization of the backoff.
+	DisableJitter bool
+	// StatusCodes are the statuses that trigger a retry. Defaults to 408,
+	// 429, 500, 502, 503 and 504.
+	StatusCodes []int
+	// RetryNonIdempotent also retries POST and PATCH requests. Only enable
+	// it when a retried request cannot create duplicates.
+	RetryNonIdempotent bool
+}
+
+// WithRetryOptions replaces the default retry policy.
+func WithRetryOptions(retry RetryOptions) Option {
+	return func(c *Client) {
+		c.retry = retry
+	}
+}
+
+func (o *RetryOptions) maxAttempts(method string, retrySafe bool) int {
+	if !o.RetryNonIdempotent && !retrySafe && !isIdempotent(method) {
+		return 1
+	}
+	if o.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return o.MaxAttempts
+}
+
+func (o *RetryOptions) shouldRetry(
+	ctx context.Context,
+	res *http.Response,
+	err error,
+) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded)
+	}
+	codes := o.StatusCodes
+	if codes == nil {
+		codes = defaultRetryStatusCodes
+	}
+	for _, code := range codes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before the next attempt, preferring the
+// delay requested by the service over the computed backoff.
+func (o *RetryOptions) delay(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if d, ok := retryAfter(res.Header); ok {
+			return d
+		}
+	}
+	base := o.RetryDelay
+	if base <= 0 {
+		base = defaultRetryDelay
+	}
+	max := o.MaxRetryDelay
+	if max <= 0 {
+		max = defaultMaxRetryDelay
+	}
+	d := base << (attempt - 1)
+	if d <= 0 || d > max {
+		d = max
+	}
+	if !o.DisableJitter {
+		d = time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+	}
+	return d
+}
+
+// retryAfter reads x-ms-retry-after-ms, retry-after-ms and Retry-After (in
+// seconds or as an HTTP date), in that order.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	for _, name := range []string{"X-Ms-Retry-After-Ms", "Retry-After-Ms"} {
+		if v := header.Get(name); v != "" {
+			if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+				return time.Duration(ms) * time.Millisecond, true
+			}
+		}
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut,
+		http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// policy returns the retry stage of the pipeline. Every attempt sends a
+// fresh copy of the request through the rest of the pipeline, so later
+// stages re-sign it.
+func (o RetryOptions) policy() Policy {
+	return func(req *http.Request, next Next) (*http.Response, error) {
+		ctx := req.Context()
+		retrySafe, _ := ctx.Value(retrySafeKey{}).(bool)
+		attempts := o.maxAttempts(req.Method, retrySafe)
+		for attempt := 1; ; attempt++ {
+			try := req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				try.Body = body
+			}
+			res, err := next(try)
+			if nre, ok := err.(*nonRetriableError); ok {
+				return nil, nre.err
+			}
+			if attempt >= attempts || !o.shouldRetry(ctx, res, err) {
+				return res, err
+			}
+			d := o.delay(attempt, res)
+			if res != nil {
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+			timer := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
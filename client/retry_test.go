@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fastRetries = WithRetryOptions(RetryOptions{
+	MaxAttempts:   3,
+	RetryDelay:    time.Millisecond,
+	MaxRetryDelay: 5 * time.Millisecond,
+})
+
+type countingCredential struct {
+	calls int
+}
+
+func (c *countingCredential) Authorize(
+	ctx context.Context,
+	method string,
+	host string,
+	resource string,
+	body []byte,
+	header http.Header,
+) error {
+	c.calls++
+	header.Set("Authorization", "signed")
+	return nil
+}
+
+func TestRetryResignsEachAttempt(t *testing.T) {
+	var calls int32
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "signed", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"a":1}`, string(body))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("x-ms-retry-after-ms", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"ok"}`))
+	}, WithRetryOptions(RetryOptions{MaxAttempts: 3}))
+	cred := &countingCredential{}
+	c.cred = cred
+	res, err := c.Do(context.Background(), &Request{
+		Method:     http.MethodPost,
+		Host:       host,
+		Path:       "/rooms",
+		RawBody:    []byte(`{"a":1}`),
+		Idempotent: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(3), calls)
+	assert.Equal(t, 3, cred.calls)
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, fastRetries)
+	err := c.Delete(context.Background(), host, "/rooms/x", "api-version=1", nil)
+	assert.True(t, HasErrorCode(err, "TooManyRequests"))
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRetrySkipsNonIdempotentByDefault(t *testing.T) {
+	var calls int32
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, fastRetries)
+	err := c.Post(context.Background(), host, "/rooms", "api-version=1", nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRetryNonIdempotentOptIn(t *testing.T) {
+	var calls int32
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}, WithRetryOptions(RetryOptions{
+		MaxAttempts:        3,
+		RetryDelay:         time.Millisecond,
+		RetryNonIdempotent: true,
+	}))
+	err := c.Post(context.Background(), host, "/rooms", "api-version=1", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetryDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}, fastRetries)
+	err := c.Get(context.Background(), host, "/rooms/x", "api-version=1", nil)
+	assert.True(t, HasErrorCode(err, "NotFound"))
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRetryAfterHeaders(t *testing.T) {
+	d, ok := retryAfter(http.Header{"Retry-After": {"2"}})
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+	d, ok = retryAfter(http.Header{"X-Ms-Retry-After-Ms": {"150"}, "Retry-After": {"2"}})
+	assert.True(t, ok)
+	assert.Equal(t, 150*time.Millisecond, d)
+	_, ok = retryAfter(http.Header{})
+	assert.False(t, ok)
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	o := RetryOptions{
+		RetryDelay:    time.Second,
+		MaxRetryDelay: 3 * time.Second,
+		DisableJitter: true,
+	}
+	assert.Equal(t, time.Second, o.delay(1, nil))
+	assert.Equal(t, 2*time.Second, o.delay(2, nil))
+	assert.Equal(t, 3*time.Second, o.delay(3, nil))
+	assert.Equal(t, 3*time.Second, o.delay(40, nil))
+}
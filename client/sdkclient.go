@@ -5,23 +5,42 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"time"
-
-	"github.com/karim-w/stdlib/httpclient"
 )
 
 type Client struct {
-	key string
+	cred          Credential
+	transport     *transport
+	retry         RetryOptions
+	perCall       []Policy
+	perRetry      []Policy
+	logger        Logger
+	applicationID string
 }
 
+// New creates a client that signs requests with an ACS access key.
 func New(
 	key string,
+	opts ...Option,
+) *Client {
+	return NewWithCredential(NewKeyCredential(key), opts...)
+}
+
+// NewWithCredential creates a client that authorizes requests with cred,
+// for example a Microsoft Entra ID credential instead of an access key.
+func NewWithCredential(
+	cred Credential,
+	opts ...Option,
 ) *Client {
-	return &Client{key}
+	c := &Client{
+		cred:      cred,
+		transport: newTransport(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func createAuthHeader(
@@ -70,51 +89,7 @@ func (c *Client) Patch(
 	reqbody interface{},
 	response interface{},
 ) error {
-	body := []byte("{}")
-	var err error
-	if reqbody != nil {
-		body, err = json.Marshal(reqbody)
-		if err != nil {
-			return err
-		}
-	} else {
-		reqbody = struct{}{}
-	}
-	date := time.Now().UTC().Format(http.TimeFormat)
-	// Compute a content hash for the 'x-ms-content-sha256' header.
-	contentHash := computeContentHash(body)
-	// Prepare a string to sign.
-	stringToSign := fmt.Sprintf("PATCH\n%s\n%s;%s;%s", resource+"?"+query, date, host, contentHash)
-
-	// Compute the signature.
-	signature := computeSignature(stringToSign, c.key)
-
-	// Concatenate the string, which will be used in the authorization header.
-	authorizationHeader := fmt.Sprintf(
-		"HMAC-SHA256 SignedHeaders=x-ms-date;host;x-ms-content-sha256&Signature=%s",
-		signature,
-	)
-	res := httpclient.Req(
-		"https://"+host+resource+"?"+query,
-	).AddHeader(
-		"x-ms-date", date,
-	).AddHeader(
-		"x-ms-content-sha256", contentHash,
-	).AddHeader(
-		"Authorization", authorizationHeader,
-	).AddHeader(
-		"Content-Type", "application/json",
-	).AddBody(
-		reqbody,
-	).Patch()
-	responseBody := res.GetBody()
-	if !res.IsSuccess() {
-		return errors.New(string(responseBody))
-	}
-	if len(responseBody) == 0 {
-		return nil
-	}
-	return json.Unmarshal(responseBody, &response)
+	return c.call(ctx, http.MethodPatch, host, resource, query, bodyOrEmpty(reqbody), response)
 }
 
 func (c *Client) Post(
@@ -125,51 +100,18 @@ func (c *Client) Post(
 	reqbody interface{},
 	response interface{},
 ) error {
-	body := []byte("{}")
-	var err error
-	if reqbody != nil {
-		body, err = json.Marshal(reqbody)
-		if err != nil {
-			return err
-		}
-	} else {
-		reqbody = struct{}{}
-	}
-	date := time.Now().UTC().Format(http.TimeFormat)
-	// Compute a content hash for the 'x-ms-content-sha256' header.
-	contentHash := computeContentHash(body)
-	// Prepare a string to sign.
-	stringToSign := fmt.Sprintf("POST\n%s\n%s;%s;%s", resource+"?"+query, date, host, contentHash)
-
-	// Compute the signature.
-	signature := computeSignature(stringToSign, c.key)
+	return c.call(ctx, http.MethodPost, host, resource, query, bodyOrEmpty(reqbody), response)
+}
 
-	// Concatenate the string, which will be used in the authorization header.
-	authorizationHeader := fmt.Sprintf(
-		"HMAC-SHA256 SignedHeaders=x-ms-date;host;x-ms-content-sha256&Signature=%s",
-		signature,
-	)
-	res := httpclient.Req(
-		"https://"+host+resource+"?"+query,
-	).AddHeader(
-		"x-ms-date", date,
-	).AddHeader(
-		"x-ms-content-sha256", contentHash,
-	).AddHeader(
-		"Authorization", authorizationHeader,
-	).AddHeader(
-		"Content-Type", "application/json",
-	).AddBody(
-		reqbody,
-	).Post()
-	responseBody := res.GetBody()
-	if !res.IsSuccess() {
-		return errors.New(string(responseBody))
-	}
-	if len(responseBody) == 0 {
-		return nil
-	}
-	return json.Unmarshal(responseBody, &response)
+func (c *Client) Put(
+	ctx context.Context,
+	host string,
+	resource string,
+	query string,
+	reqbody interface{},
+	response interface{},
+) error {
+	return c.call(ctx, http.MethodPut, host, resource, query, bodyOrEmpty(reqbody), response)
 }
 
 func (c *Client) Delete(
@@ -179,36 +121,7 @@ func (c *Client) Delete(
 	query string,
 	response interface{},
 ) error {
-	body := []byte("{}")
-	reqBody := struct{}{}
-	date := time.Now().UTC().Format(http.TimeFormat)
-	contentHash, authHeader := createAuthHeader(
-		"DELETE",
-		host,
-		resource+"?"+query,
-		date,
-		c.key,
-		body,
-	)
-	res := httpclient.Req(
-		"https://"+host+resource+"?"+query,
-	).AddHeader(
-		"x-ms-date", date,
-	).AddHeader(
-		"x-ms-content-sha256", contentHash,
-	).AddHeader(
-		"Authorization", authHeader,
-	).AddHeader(
-		"Content-Type", "application/json",
-	).AddBody(reqBody).Del()
-	responseBody := res.GetBody()
-	if !res.IsSuccess() {
-		return errors.New(string(responseBody))
-	}
-	if len(responseBody) == 0 {
-		return nil
-	}
-	return json.Unmarshal(responseBody, &response)
+	return c.call(ctx, http.MethodDelete, host, resource, query, nil, response)
 }
 
 func (c *Client) Get(
@@ -218,34 +131,47 @@ func (c *Client) Get(
 	query string,
 	response interface{},
 ) error {
-	body := []byte("{}")
-	reqBody := struct{}{}
-	date := time.Now().UTC().Format(http.TimeFormat)
-	contentHash, authHeader := createAuthHeader(
-		"GET",
-		host,
-		resource+"?"+query,
-		date,
-		c.key,
-		body,
-	)
-	res := httpclient.Req(
-		"https://"+host+resource+"?"+query,
-	).AddHeader(
-		"x-ms-date", date,
-	).AddHeader(
-		"x-ms-content-sha256", contentHash,
-	).AddHeader(
-		"Authorization", authHeader,
-	).AddHeader(
-		"Content-Type", "application/json",
-	).AddBody(reqBody).Get()
-	responseBody := res.GetBody()
-	if !res.IsSuccess() {
-		return errors.New(string(responseBody))
+	return c.call(ctx, http.MethodGet, host, resource, query, nil, response)
+}
+
+// Head reports whether the resource exists; a 404 is returned as a
+// *ResponseError like any other failure.
+func (c *Client) Head(
+	ctx context.Context,
+	host string,
+	resource string,
+	query string,
+) error {
+	return c.call(ctx, http.MethodHead, host, resource, query, nil, nil)
+}
+
+func (c *Client) call(
+	ctx context.Context,
+	method string,
+	host string,
+	resource string,
+	query string,
+	reqbody interface{},
+	response interface{},
+) error {
+	res, err := c.Do(ctx, &Request{
+		Method: method,
+		Host:   host,
+		Path:   resource,
+		Query:  query,
+		Body:   reqbody,
+	})
+	if err != nil {
+		return err
 	}
-	if len(responseBody) == 0 {
-		return nil
+	return res.Decode(response)
+}
+
+// bodyOrEmpty keeps sending "{}" for POST, PUT and PATCH calls without a
+// body, which some ACS operations require.
+func bodyOrEmpty(reqbody interface{}) interface{} {
+	if reqbody == nil {
+		return struct{}{}
 	}
-	return json.Unmarshal(responseBody, &response)
+	return reqbody
 }
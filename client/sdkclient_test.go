@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*Client, string) {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+	opts = append([]Option{
+		WithHTTPClient(srv.Client()),
+		WithRetryOptions(RetryOptions{MaxAttempts: 1}),
+	}, opts...)
+	return New("", opts...), strings.TrimPrefix(srv.URL, "https://")
+}
+
+func TestGetHonorsContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Get(ctx, host, "/identities/x", "api-version=1", nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestPostHonorsCanceledContext(t *testing.T) {
+	called := false
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := c.Post(ctx, host, "/identities", "api-version=1", nil, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, called)
+}
+
+func TestPostDecodesResponse(t *testing.T) {
+	c, host := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NotEmpty(t, r.Header.Get("x-ms-date"))
+		assert.NotEmpty(t, r.Header.Get("x-ms-content-sha256"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "HMAC-SHA256 "))
+		w.Write([]byte(`{"id":"abc"}`))
+	})
+	var response struct {
+		ID string `json:"id"`
+	}
+	err := c.Post(context.Background(), host, "/identities", "api-version=1", nil, &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", response.ID)
+}
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests = append(rt.requests, req)
+	return &http.Response{
+		StatusCode: http.StatusNoContent,
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func TestWithTransport(t *testing.T) {
+	rt := &recordingTransport{}
+	c := New("", WithTransport(rt))
+	err := c.Delete(context.Background(), "example.com", "/identities/x", "api-version=1", nil)
+	assert.Nil(t, err)
+	assert.Len(t, rt.requests, 1)
+	assert.Equal(t, http.MethodDelete, rt.requests[0].Method)
+	assert.Equal(t, "https://example.com/identities/x?api-version=1", rt.requests[0].URL.String())
+}
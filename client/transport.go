@@ -0,0 +1,20 @@
+package client
+
+import (
+	"net/http"
+)
+
+// transport is the last stage of the pipeline. It sends requests through
+// net/http; every request carries the caller's context so deadlines and
+// cancellation abort in-flight calls.
+type transport struct {
+	httpClient *http.Client
+}
+
+func newTransport() *transport {
+	return &transport{httpClient: &http.Client{}}
+}
+
+func (t *transport) do(req *http.Request) (*http.Response, error) {
+	return t.httpClient.Do(req)
+}
@@ -0,0 +1,241 @@
+package credential
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ERR_NO_TOKEN_PROVIDED = errors.New("no token provided")
+	ERR_EXPIRED_TOKEN     = errors.New("token expired")
+	ERR_INVALID_TOKEN     = errors.New("token is not a valid JWT")
+)
+
+// defaultRefreshWindow is how long before expiry a token is refreshed.
+const defaultRefreshWindow = 10 * time.Minute
+
+// refreshTimeout bounds a single refresh, which no caller can cancel.
+const refreshTimeout = time.Minute
+
+// AccessToken is an ACS user access token and its expiry.
+type AccessToken struct {
+	Token     string
+	ExpiresOn time.Time
+}
+
+// CommunicationTokenCredential supplies user access tokens to the
+// bearer-authenticated ACS APIs such as chat. Implementations are safe for
+// concurrent use.
+type CommunicationTokenCredential interface {
+	GetToken(ctx context.Context) (AccessToken, error)
+}
+
+// RefreshWindowSetter is implemented by credentials that refresh tokens
+// before they expire, such as those from NewRefreshingTokenCredential.
+type RefreshWindowSetter interface {
+	SetRefreshWindow(window time.Duration)
+}
+
+// TokenRefresher fetches a new token. When the returned ExpiresOn is zero
+// it is read from the token's exp claim.
+type TokenRefresher func(ctx context.Context) (AccessToken, error)
+
+// RefreshOptions tunes NewRefreshingTokenCredential.
+type RefreshOptions struct {
+	// InitialToken is served until it enters the refresh window, which
+	// saves a refresh on first use.
+	InitialToken *AccessToken
+	// RefreshWindow is how long before expiry the token is refreshed.
+	// Defaults to 10 minutes.
+	RefreshWindow time.Duration
+}
+
+// DecodeExpiry reads the exp claim of a JWT access token.
+func DecodeExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, ERR_INVALID_TOKEN
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, ERR_INVALID_TOKEN
+	}
+	var claims struct {
+		Exp *int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, ERR_INVALID_TOKEN
+	}
+	return time.Unix(*claims.Exp, 0), nil
+}
+
+type staticCredential struct {
+	token AccessToken
+}
+
+// NewStaticTokenCredential serves a single JWT access token whose expiry is
+// read from its exp claim. Once it expires GetToken fails with
+// ERR_EXPIRED_TOKEN.
+func NewStaticTokenCredential(token string) (CommunicationTokenCredential, error) {
+	if token == "" {
+		return nil, ERR_NO_TOKEN_PROVIDED
+	}
+	expiresOn, err := DecodeExpiry(token)
+	if err != nil {
+		return nil, err
+	}
+	return &staticCredential{AccessToken{token, expiresOn}}, nil
+}
+
+// NewStaticTokenCredentialWithExpiry serves a single token with a known
+// expiry, for tokens that cannot be decoded. A zero expiresOn never
+// expires.
+func NewStaticTokenCredentialWithExpiry(
+	token string,
+	expiresOn time.Time,
+) CommunicationTokenCredential {
+	return &staticCredential{AccessToken{token, expiresOn}}
+}
+
+func (s *staticCredential) GetToken(ctx context.Context) (AccessToken, error) {
+	if s.token.Token == "" {
+		return AccessToken{}, ERR_NO_TOKEN_PROVIDED
+	}
+	if !s.token.ExpiresOn.IsZero() && !time.Now().Before(s.token.ExpiresOn) {
+		return AccessToken{}, ERR_EXPIRED_TOKEN
+	}
+	return s.token, nil
+}
+
+type refreshingCredential struct {
+	refresher     TokenRefresher
+	refreshWindow time.Duration
+
+	mu         sync.Mutex
+	token      AccessToken
+	refreshing *tokenRefresh
+}
+
+// tokenRefresh is an in-flight refresh that concurrent callers wait on
+// instead of issuing their own.
+type tokenRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRefreshingTokenCredential serves tokens from refresher, refreshing
+// them proactively before they expire. Only one refresh runs at a time:
+// callers keep using the current token while it is still valid and
+// otherwise wait for the in-flight refresh.
+func NewRefreshingTokenCredential(
+	refresher TokenRefresher,
+	opts *RefreshOptions,
+) CommunicationTokenCredential {
+	c := &refreshingCredential{
+		refresher:     refresher,
+		refreshWindow: defaultRefreshWindow,
+	}
+	if opts != nil {
+		if opts.InitialToken != nil {
+			c.token = *opts.InitialToken
+		}
+		if opts.RefreshWindow > 0 {
+			c.refreshWindow = opts.RefreshWindow
+		}
+	}
+	return c
+}
+
+func (c *refreshingCredential) GetToken(ctx context.Context) (AccessToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token.Token == "" || !time.Now().Add(c.refreshWindow).Before(c.token.ExpiresOn) {
+		err := c.refreshLocked(ctx)
+		// a failed proactive refresh is not fatal while the current
+		// token is still valid.
+		if err != nil && !c.validLocked() {
+			return AccessToken{}, err
+		}
+	}
+	if c.token.Token == "" {
+		return AccessToken{}, ERR_NO_TOKEN_PROVIDED
+	}
+	return c.token, nil
+}
+
+// SetRefreshWindow changes how long before expiry the token is refreshed.
+// A window that is not positive restores the 10 minute default.
+func (c *refreshingCredential) SetRefreshWindow(window time.Duration) {
+	if window <= 0 {
+		window = defaultRefreshWindow
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshWindow = window
+}
+
+func (c *refreshingCredential) validLocked() bool {
+	return c.token.Token != "" && time.Now().Before(c.token.ExpiresOn)
+}
+
+// refreshLocked refreshes the token or joins the refresh already in
+// flight. It must be called with c.mu held; the lock is released while
+// waiting. The refresh itself runs detached from ctx, so a caller that
+// gives up does not fail the refresh for the callers still waiting on it.
+func (c *refreshingCredential) refreshLocked(ctx context.Context) error {
+	flight := c.refreshing
+	if flight == nil {
+		flight = &tokenRefresh{done: make(chan struct{})}
+		c.refreshing = flight
+		go c.refresh(detachedContext{ctx}, flight)
+	} else if c.validLocked() {
+		return nil
+	}
+	c.mu.Unlock()
+	defer c.mu.Lock()
+	select {
+	case <-flight.done:
+		return flight.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refresh runs the refresher for flight and publishes its result.
+func (c *refreshingCredential) refresh(ctx context.Context, flight *tokenRefresh) {
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+	token, err := c.refresher(ctx)
+	if err == nil && token.ExpiresOn.IsZero() {
+		// an undecodable token without an expiry is refreshed on every
+		// call rather than cached forever.
+		token.ExpiresOn, _ = DecodeExpiry(token.Token)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = nil
+	if err == nil {
+		c.token = token
+	}
+	flight.err = err
+	close(flight.done)
+}
+
+// detachedContext keeps the values of the context that started a refresh
+// but not its deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (d detachedContext) Value(key any) any {
+	return d.parent.Value(key)
+}
@@ -0,0 +1,173 @@
+package credential
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func jwt(exp time.Time) string {
+	payload := `{"skypeid":"acs:user","exp":` + strconv.FormatInt(exp.Unix(), 10) + `}`
+	return "eyJhbGciOiJSUzI1NiJ9." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload)) +
+		".signature"
+}
+
+func TestDecodeExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	got, err := DecodeExpiry(jwt(exp))
+	assert.Nil(t, err)
+	assert.True(t, exp.Equal(got))
+
+	_, err = DecodeExpiry("not-a-jwt")
+	assert.True(t, errors.Is(err, ERR_INVALID_TOKEN))
+	_, err = DecodeExpiry("a.e30.c")
+	assert.True(t, errors.Is(err, ERR_INVALID_TOKEN))
+}
+
+func TestStaticTokenCredential(t *testing.T) {
+	token := jwt(time.Now().Add(time.Hour))
+	cred, err := NewStaticTokenCredential(token)
+	assert.Nil(t, err)
+	got, err := cred.GetToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, token, got.Token)
+
+	cred, err = NewStaticTokenCredential(jwt(time.Now().Add(-time.Hour)))
+	assert.Nil(t, err)
+	_, err = cred.GetToken(context.Background())
+	assert.True(t, errors.Is(err, ERR_EXPIRED_TOKEN))
+
+	_, err = NewStaticTokenCredential("")
+	assert.True(t, errors.Is(err, ERR_NO_TOKEN_PROVIDED))
+}
+
+func TestRefreshingCredentialCachesUntilRefreshWindow(t *testing.T) {
+	var calls int32
+	cred := NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		atomic.AddInt32(&calls, 1)
+		// expiry is decoded from the exp claim
+		return AccessToken{Token: jwt(time.Now().Add(time.Hour))}, nil
+	}, nil)
+	for i := 0; i < 3; i++ {
+		_, err := cred.GetToken(context.Background())
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(1), calls)
+
+	cred = NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		atomic.AddInt32(&calls, 1)
+		return AccessToken{Token: jwt(time.Now().Add(time.Hour))}, nil
+	}, &RefreshOptions{RefreshWindow: 2 * time.Hour})
+	cred.GetToken(context.Background())
+	cred.GetToken(context.Background())
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRefreshingCredentialSetRefreshWindow(t *testing.T) {
+	var calls int32
+	cred := NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		atomic.AddInt32(&calls, 1)
+		return AccessToken{Token: jwt(time.Now().Add(time.Hour))}, nil
+	}, &RefreshOptions{
+		InitialToken: &AccessToken{Token: "initial", ExpiresOn: time.Now().Add(time.Minute)},
+	})
+	cred.(RefreshWindowSetter).SetRefreshWindow(30 * time.Second)
+	token, err := cred.GetToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "initial", token.Token)
+	assert.Equal(t, int32(0), calls)
+}
+
+func TestRefreshingCredentialSingleFlight(t *testing.T) {
+	var calls int32
+	cred := NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return AccessToken{Token: "fresh", ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := cred.GetToken(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, "fresh", token.Token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRefreshingCredentialFailure(t *testing.T) {
+	boom := errors.New("boom")
+	cred := NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		return AccessToken{}, boom
+	}, &RefreshOptions{
+		InitialToken: &AccessToken{Token: "current", ExpiresOn: time.Now().Add(time.Minute)},
+	})
+	token, err := cred.GetToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "current", token.Token)
+
+	cred = NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		return AccessToken{}, boom
+	}, nil)
+	_, err = cred.GetToken(context.Background())
+	assert.True(t, errors.Is(err, boom))
+}
+
+func TestRefreshingCredentialWaiterHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	cred := NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		close(started)
+		<-release
+		return AccessToken{Token: "fresh", ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}, nil)
+	go cred.GetToken(context.Background())
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cred.GetToken(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	close(release)
+}
+
+func TestRefreshingCredentialSurvivesCanceledLeader(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	cred := NewRefreshingTokenCredential(func(ctx context.Context) (AccessToken, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return AccessToken{}, err
+		}
+		return AccessToken{Token: "fresh", ExpiresOn: time.Now().Add(time.Hour)}, nil
+	}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := cred.GetToken(ctx)
+		leader <- err
+	}()
+	<-started
+	follower := make(chan AccessToken)
+	go func() {
+		token, err := cred.GetToken(context.Background())
+		assert.Nil(t, err)
+		follower <- token
+	}()
+	cancel()
+	assert.True(t, errors.Is(<-leader, context.Canceled))
+	close(release)
+	assert.Equal(t, "fresh", (<-follower).Token)
+}
@@ -2,21 +2,12 @@ module github.com/karim-w/go-azure-communication-services
 
 go 1.19
 
-require (
-	github.com/karim-w/stdlib v0.4.0
-	github.com/stretchr/testify v1.8.0
-)
+require github.com/stretchr/testify v1.8.0
 
 require (
-	code.cloudfoundry.org/clock v1.0.0 // indirect
-	github.com/BetaLixT/appInsightsTrace v0.2.3 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/gofrs/uuid v4.2.0+incompatible // indirect
-	github.com/lib/pq v1.10.7 // indirect
-	github.com/microsoft/ApplicationInsights-Go v0.4.4 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	go.uber.org/atomic v1.9.0 // indirect
-	go.uber.org/multierr v1.8.0 // indirect
-	go.uber.org/zap v1.23.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
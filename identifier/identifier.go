@@ -0,0 +1,164 @@
+// Package identifier models the participants ACS APIs refer to: ACS users,
+// phone numbers, Microsoft Teams users and apps, and identifiers of any
+// other kind. Every identifier has a raw id, a string form such as
+// "8:acs:<resource>_<user>" or "4:+14255550123" that Parse turns back into
+// the typed identifier.
+package identifier
+
+import "strings"
+
+type Kind string
+
+const (
+	KindCommunicationUser  Kind = "communicationUser"
+	KindPhoneNumber        Kind = "phoneNumber"
+	KindMicrosoftTeamsUser Kind = "microsoftTeamsUser"
+	KindMicrosoftTeamsApp  Kind = "microsoftTeamsApp"
+	KindUnknown            Kind = "unknown"
+)
+
+// Cloud is the Microsoft cloud a Teams user or app belongs to.
+type Cloud string
+
+const (
+	CloudPublic Cloud = "public"
+	CloudDod    Cloud = "dod"
+	CloudGcch   Cloud = "gcch"
+)
+
+const (
+	phoneNumberPrefix        = "4:"
+	teamsAnonymousUserPrefix = "8:teamsvisitor:"
+	teamsPublicUserPrefix    = "8:orgid:"
+	teamsDodUserPrefix       = "8:dod:"
+	teamsGcchUserPrefix      = "8:gcch:"
+	teamsPublicAppPrefix     = "28:orgid:"
+	teamsDodAppPrefix        = "28:dod:"
+	teamsGcchAppPrefix       = "28:gcch:"
+	acsUserPrefix            = "8:acs:"
+	acsDodUserPrefix         = "8:dod-acs:"
+	acsGcchUserPrefix        = "8:gcch-acs:"
+	spoolUserPrefix          = "8:spool:"
+)
+
+// CommunicationIdentifier is implemented by CommunicationUser, PhoneNumber,
+// MicrosoftTeamsUser, MicrosoftTeamsApp and Unknown.
+type CommunicationIdentifier interface {
+	Kind() Kind
+	RawID() string
+}
+
+// CommunicationUser is a user created through the identity API.
+type CommunicationUser struct {
+	ID string `json:"id"`
+}
+
+func (u CommunicationUser) Kind() Kind { return KindCommunicationUser }
+
+func (u CommunicationUser) RawID() string { return u.ID }
+
+// PhoneNumber is a PSTN participant. Value is in E.164 format, e.g.
+// "+14255550123".
+type PhoneNumber struct {
+	Value string `json:"value"`
+}
+
+func (p PhoneNumber) Kind() Kind { return KindPhoneNumber }
+
+func (p PhoneNumber) RawID() string { return phoneNumberPrefix + p.Value }
+
+// MicrosoftTeamsUser is a Teams user, identified by their Entra ID object
+// id, or an anonymous Teams meeting guest.
+type MicrosoftTeamsUser struct {
+	UserID      string `json:"userId"`
+	IsAnonymous bool   `json:"isAnonymous,omitempty"`
+	Cloud       Cloud  `json:"cloud,omitempty"`
+}
+
+func (u MicrosoftTeamsUser) Kind() Kind { return KindMicrosoftTeamsUser }
+
+func (u MicrosoftTeamsUser) RawID() string {
+	if u.IsAnonymous {
+		return teamsAnonymousUserPrefix + u.UserID
+	}
+	switch u.Cloud {
+	case CloudDod:
+		return teamsDodUserPrefix + u.UserID
+	case CloudGcch:
+		return teamsGcchUserPrefix + u.UserID
+	}
+	return teamsPublicUserPrefix + u.UserID
+}
+
+// MicrosoftTeamsApp is a Teams app such as a bot, identified by its
+// Entra ID application id.
+type MicrosoftTeamsApp struct {
+	AppID string `json:"appId"`
+	Cloud Cloud  `json:"cloud,omitempty"`
+}
+
+func (a MicrosoftTeamsApp) Kind() Kind { return KindMicrosoftTeamsApp }
+
+func (a MicrosoftTeamsApp) RawID() string {
+	switch a.Cloud {
+	case CloudDod:
+		return teamsDodAppPrefix + a.AppID
+	case CloudGcch:
+		return teamsGcchAppPrefix + a.AppID
+	}
+	return teamsPublicAppPrefix + a.AppID
+}
+
+// Unknown is an identifier this package has no model for. ID is its raw id.
+type Unknown struct {
+	ID string `json:"id"`
+}
+
+func (u Unknown) Kind() Kind { return KindUnknown }
+
+func (u Unknown) RawID() string { return u.ID }
+
+// Parse returns the typed identifier a raw id stands for, or Unknown when
+// the raw id has no recognized prefix.
+func Parse(rawID string) CommunicationIdentifier {
+	if strings.HasPrefix(rawID, phoneNumberPrefix) {
+		return PhoneNumber{Value: strings.TrimPrefix(rawID, phoneNumberPrefix)}
+	}
+	prefix, suffix, ok := cutPrefix(rawID)
+	if !ok {
+		return Unknown{ID: rawID}
+	}
+	switch prefix {
+	case teamsAnonymousUserPrefix:
+		return MicrosoftTeamsUser{UserID: suffix, IsAnonymous: true}
+	case teamsPublicUserPrefix:
+		return MicrosoftTeamsUser{UserID: suffix, Cloud: CloudPublic}
+	case teamsDodUserPrefix:
+		return MicrosoftTeamsUser{UserID: suffix, Cloud: CloudDod}
+	case teamsGcchUserPrefix:
+		return MicrosoftTeamsUser{UserID: suffix, Cloud: CloudGcch}
+	case teamsPublicAppPrefix:
+		return MicrosoftTeamsApp{AppID: suffix, Cloud: CloudPublic}
+	case teamsDodAppPrefix:
+		return MicrosoftTeamsApp{AppID: suffix, Cloud: CloudDod}
+	case teamsGcchAppPrefix:
+		return MicrosoftTeamsApp{AppID: suffix, Cloud: CloudGcch}
+	case acsUserPrefix, acsDodUserPrefix, acsGcchUserPrefix, spoolUserPrefix:
+		return CommunicationUser{ID: rawID}
+	}
+	return Unknown{ID: rawID}
+}
+
+// cutPrefix splits "8:acs:rest" into "8:acs:" and "rest".
+func cutPrefix(rawID string) (string, string, bool) {
+	first := strings.Index(rawID, ":")
+	if first < 0 {
+		return "", "", false
+	}
+	second := strings.Index(rawID[first+1:], ":")
+	if second < 0 {
+		return "", "", false
+	}
+	end := first + 1 + second + 1
+	return rawID[:end], rawID[end:], true
+}
@@ -0,0 +1,91 @@
+package identifier
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAndFormat(t *testing.T) {
+	cases := map[string]CommunicationIdentifier{
+		"8:acs:resource_user":   CommunicationUser{ID: "8:acs:resource_user"},
+		"8:spool:resource_user": CommunicationUser{ID: "8:spool:resource_user"},
+		"8:dod-acs:resource_u":  CommunicationUser{ID: "8:dod-acs:resource_u"},
+		"8:gcch-acs:resource_u": CommunicationUser{ID: "8:gcch-acs:resource_u"},
+		"4:+14255550123":        PhoneNumber{Value: "+14255550123"},
+		"8:orgid:user":          MicrosoftTeamsUser{UserID: "user", Cloud: CloudPublic},
+		"8:dod:user":            MicrosoftTeamsUser{UserID: "user", Cloud: CloudDod},
+		"8:gcch:user":           MicrosoftTeamsUser{UserID: "user", Cloud: CloudGcch},
+		"8:teamsvisitor:guest":  MicrosoftTeamsUser{UserID: "guest", IsAnonymous: true},
+		"28:orgid:app":          MicrosoftTeamsApp{AppID: "app", Cloud: CloudPublic},
+		"28:dod:app":            MicrosoftTeamsApp{AppID: "app", Cloud: CloudDod},
+		"28:gcch:app":           MicrosoftTeamsApp{AppID: "app", Cloud: CloudGcch},
+		"48:something":          Unknown{ID: "48:something"},
+		"8:other:thing":         Unknown{ID: "8:other:thing"},
+		"plain":                 Unknown{ID: "plain"},
+	}
+	for rawID, want := range cases {
+		got := Parse(rawID)
+		assert.Equal(t, want, got, rawID)
+		assert.Equal(t, rawID, got.RawID(), rawID)
+	}
+}
+
+func TestDefaultCloudIsPublic(t *testing.T) {
+	assert.Equal(t, "8:orgid:user", MicrosoftTeamsUser{UserID: "user"}.RawID())
+	assert.Equal(t, "28:orgid:app", MicrosoftTeamsApp{AppID: "app"}.RawID())
+}
+
+func TestMarshalJSON(t *testing.T) {
+	cases := map[string]Identifier{
+		`{"rawId":"8:acs:r_u","communicationUser":{"id":"8:acs:r_u"}}`:              New(CommunicationUser{ID: "8:acs:r_u"}),
+		`{"rawId":"4:+1425","phoneNumber":{"value":"+1425"}}`:                       New(PhoneNumber{Value: "+1425"}),
+		`{"rawId":"8:gcch:u","microsoftTeamsUser":{"userId":"u","cloud":"gcch"}}`:   New(MicrosoftTeamsUser{UserID: "u", Cloud: CloudGcch}),
+		`{"rawId":"28:orgid:a","microsoftTeamsApp":{"appId":"a","cloud":"public"}}`: New(MicrosoftTeamsApp{AppID: "a", Cloud: CloudPublic}),
+		`{"rawId":"48:x"}`: New(Unknown{ID: "48:x"}),
+		`null`:             {},
+	}
+	for want, id := range cases {
+		got, err := json.Marshal(id)
+		assert.Nil(t, err)
+		assert.JSONEq(t, want, string(got))
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	cases := map[string]CommunicationIdentifier{
+		`{"rawId":"8:acs:r_u","kind":"communicationUser","communicationUser":{"id":"8:acs:r_u"}}`: CommunicationUser{ID: "8:acs:r_u"},
+		`{"rawId":"4:+1425","phoneNumber":{"value":"+1425"}}`:                                     PhoneNumber{Value: "+1425"},
+		`{"rawId":"8:teamsvisitor:g","microsoftTeamsUser":{"userId":"g","isAnonymous":true}}`:     MicrosoftTeamsUser{UserID: "g", IsAnonymous: true},
+		`{"microsoftTeamsApp":{"appId":"a","cloud":"dod"}}`:                                       MicrosoftTeamsApp{AppID: "a", Cloud: CloudDod},
+		`{"rawId":"8:orgid:u"}`:             MicrosoftTeamsUser{UserID: "u", Cloud: CloudPublic},
+		`{"rawId":"48:x","kind":"unknown"}`: Unknown{ID: "48:x"},
+		`null`:                              nil,
+	}
+	for data, want := range cases {
+		var id Identifier
+		assert.Nil(t, json.Unmarshal([]byte(data), &id), data)
+		assert.Equal(t, want, id.CommunicationIdentifier, data)
+	}
+}
+
+func TestZeroIdentifier(t *testing.T) {
+	var id Identifier
+	assert.True(t, id.IsZero())
+	assert.Equal(t, "", id.RawID())
+	assert.Equal(t, Kind(""), id.Kind())
+}
+
+func TestModelsRoundTrip(t *testing.T) {
+	type participant struct {
+		CommunicationIdentifier Identifier `json:"communicationIdentifier"`
+	}
+	in := participant{FromRawID("4:+14255550123")}
+	data, err := json.Marshal(in)
+	assert.Nil(t, err)
+	var out participant
+	assert.Nil(t, json.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+	assert.Equal(t, KindPhoneNumber, out.CommunicationIdentifier.Kind())
+}
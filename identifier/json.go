@@ -0,0 +1,100 @@
+package identifier
+
+import "encoding/json"
+
+// Identifier holds any CommunicationIdentifier and converts it to and from
+// the ACS wire format. It is the type used by the chat and rooms models.
+// Switch on the embedded CommunicationIdentifier to get the typed value.
+type Identifier struct {
+	CommunicationIdentifier
+}
+
+// New wraps id for use in request models.
+func New(id CommunicationIdentifier) Identifier {
+	return Identifier{id}
+}
+
+// FromRawID wraps the identifier rawID stands for.
+func FromRawID(rawID string) Identifier {
+	return Identifier{Parse(rawID)}
+}
+
+// IsZero reports whether i holds no identifier.
+func (i Identifier) IsZero() bool {
+	return i.CommunicationIdentifier == nil
+}
+
+// Kind returns the kind of the identifier, or "" if i is empty.
+func (i Identifier) Kind() Kind {
+	if i.IsZero() {
+		return ""
+	}
+	return i.CommunicationIdentifier.Kind()
+}
+
+// RawID returns the raw id of the identifier, or "" if i is empty.
+func (i Identifier) RawID() string {
+	if i.IsZero() {
+		return ""
+	}
+	return i.CommunicationIdentifier.RawID()
+}
+
+type wireIdentifier struct {
+	RawID              string              `json:"rawId,omitempty"`
+	Kind               Kind                `json:"kind,omitempty"`
+	CommunicationUser  *CommunicationUser  `json:"communicationUser,omitempty"`
+	PhoneNumber        *PhoneNumber        `json:"phoneNumber,omitempty"`
+	MicrosoftTeamsUser *MicrosoftTeamsUser `json:"microsoftTeamsUser,omitempty"`
+	MicrosoftTeamsApp  *MicrosoftTeamsApp  `json:"microsoftTeamsApp,omitempty"`
+}
+
+func (i Identifier) MarshalJSON() ([]byte, error) {
+	if i.IsZero() {
+		return []byte("null"), nil
+	}
+	wire := wireIdentifier{RawID: i.RawID()}
+	switch id := i.CommunicationIdentifier.(type) {
+	case CommunicationUser:
+		wire.CommunicationUser = &id
+	case *CommunicationUser:
+		wire.CommunicationUser = id
+	case PhoneNumber:
+		wire.PhoneNumber = &id
+	case *PhoneNumber:
+		wire.PhoneNumber = id
+	case MicrosoftTeamsUser:
+		wire.MicrosoftTeamsUser = &id
+	case *MicrosoftTeamsUser:
+		wire.MicrosoftTeamsUser = id
+	case MicrosoftTeamsApp:
+		wire.MicrosoftTeamsApp = &id
+	case *MicrosoftTeamsApp:
+		wire.MicrosoftTeamsApp = id
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON prefers the kind-specific object and falls back to parsing
+// the raw id, so identifiers from any API version decode.
+func (i *Identifier) UnmarshalJSON(data []byte) error {
+	var wire wireIdentifier
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	switch {
+	case wire.CommunicationUser != nil:
+		i.CommunicationIdentifier = *wire.CommunicationUser
+	case wire.PhoneNumber != nil:
+		i.CommunicationIdentifier = *wire.PhoneNumber
+	case wire.MicrosoftTeamsUser != nil:
+		i.CommunicationIdentifier = *wire.MicrosoftTeamsUser
+	case wire.MicrosoftTeamsApp != nil:
+		i.CommunicationIdentifier = *wire.MicrosoftTeamsApp
+	case wire.RawID != "":
+		i.CommunicationIdentifier = Parse(wire.RawID)
+	default:
+		i.CommunicationIdentifier = nil
+	}
+	return nil
+}
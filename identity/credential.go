@@ -0,0 +1,30 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/karim-w/go-azure-communication-services/credential"
+)
+
+// NewTokenCredential returns a credential that issues tokens for acsId
+// through client and refreshes them before they expire.
+func NewTokenCredential(
+	client Identity,
+	acsId string,
+	opts *IssueTokenOptions,
+	refresh *credential.RefreshOptions,
+) credential.CommunicationTokenCredential {
+	return credential.NewRefreshingTokenCredential(
+		func(ctx context.Context) (credential.AccessToken, error) {
+			user, err := client.IssueAccessToken(ctx, acsId, opts)
+			if err != nil {
+				return credential.AccessToken{}, err
+			}
+			return credential.AccessToken{
+				Token:     user.Token,
+				ExpiresOn: user.ExpiresOn,
+			}, nil
+		},
+		refresh,
+	)
+}
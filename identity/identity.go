@@ -2,8 +2,11 @@ package identity
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 
 	"github.com/karim-w/go-azure-communication-services/client"
+	"github.com/karim-w/go-azure-communication-services/credential"
 )
 
 type Identity interface {
@@ -24,6 +27,16 @@ type Identity interface {
 		ctx context.Context,
 		acsId string,
 	) error
+	GetIdentity(
+		ctx context.Context,
+		acsId string,
+	) (*CommunicationIdentity, error)
+	GetTokenForTeamsUser(
+		ctx context.Context,
+		teamsToken string,
+		appID string,
+		userObjectID string,
+	) (*credential.AccessToken, error)
 }
 
 type _Identity struct {
@@ -31,17 +44,49 @@ type _Identity struct {
 	host   string
 }
 
+// New creates an identity client signing requests with key. host is
+// either the resource host or a full endpoint URL such as
+// "http://localhost:8080/acs"; see client.ParseEndpoint.
 func New(
 	host string,
 	key string,
+	opts ...client.Option,
 ) Identity {
-	client := client.New(key)
+	client := client.New(key, opts...)
 	return &_Identity{
 		client: client,
 		host:   host,
 	}
 }
 
+// NewWithCredential creates an identity client that authorizes requests
+// with cred, such as a Microsoft Entra ID credential.
+func NewWithCredential(
+	host string,
+	cred client.Credential,
+	opts ...client.Option,
+) Identity {
+	return &_Identity{
+		client: client.NewWithCredential(cred, opts...),
+		host:   host,
+	}
+}
+
+// NewFromConnectionString creates an identity client from an ACS
+// connection string.
+func NewFromConnectionString(
+	connectionString string,
+	opts ...client.Option,
+) (Identity, error) {
+	cs, err := client.ParseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	return New(cs.Endpoint, cs.AccessKey, opts...), nil
+}
+
+// CreateIdentity creates an identity, issuing a token for it when opts
+// has scopes. opts may be nil.
 func (i *_Identity) CreateIdentity(
 	ctx context.Context,
 	opts *CreateIdentityOptions,
@@ -50,23 +95,31 @@ func (i *_Identity) CreateIdentity(
 	if err != nil {
 		return nil, err
 	}
+	var reqbody interface{}
+	if opts != nil {
+		reqbody = opts
+	}
 	var response createIdentityResponse
 	err = i.client.Post(
 		ctx,
 		i.host,
 		"/identities",
 		"api-version="+apiVersion,
-		opts,
+		reqbody,
 		&response,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &ACSIdentity{
-		ID:        response.Identity.ID,
-		Token:     response.AccessToken.Token,
-		ExpiresOn: response.AccessToken.ExpiresOn,
-	}, nil
+	user := &ACSIdentity{
+		ID:       response.Identity.ID,
+		CustomID: response.Identity.CustomID,
+	}
+	if response.AccessToken != nil {
+		user.Token = response.AccessToken.Token
+		user.ExpiresOn = response.AccessToken.ExpiresOn
+	}
+	return user, nil
 }
 
 func (i *_Identity) IssueAccessToken(
@@ -81,7 +134,7 @@ func (i *_Identity) IssueAccessToken(
 	err := i.client.Post(
 		ctx,
 		i.host,
-		"/identities/"+acsId+"/:issueAccessToken",
+		"/identities/"+url.PathEscape(acsId)+"/:issueAccessToken",
 		"api-version="+apiVersion,
 		opts,
 		&response,
@@ -103,13 +156,31 @@ func (i *_Identity) RevokeAccessToken(
 	return i.client.Post(
 		ctx,
 		i.host,
-		"/identities/"+acsId+"/:revokeAccessTokens",
+		"/identities/"+url.PathEscape(acsId)+"/:revokeAccessTokens",
 		"api-version="+apiVersion,
 		nil,
 		nil,
 	)
 }
 
+func (i *_Identity) GetIdentity(
+	ctx context.Context,
+	acsId string,
+) (*CommunicationIdentity, error) {
+	var response CommunicationIdentity
+	err := i.client.Get(
+		ctx,
+		i.host,
+		"/identities/"+url.PathEscape(acsId),
+		"api-version="+apiVersion,
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
 func (i *_Identity) DeleteIdentity(
 	ctx context.Context,
 	acsId string,
@@ -117,9 +188,49 @@ func (i *_Identity) DeleteIdentity(
 	return i.client.Delete(
 		ctx,
 		i.host,
-		"/identities/"+acsId,
+		"/identities/"+url.PathEscape(acsId),
 		"api-version="+apiVersion,
 		nil,
 	)
 	// return nil
 }
+
+// GetTokenForTeamsUser exchanges teamsToken, an Entra ID access token of a
+// Teams user issued to the Entra application appID, for an ACS token of
+// that user. userObjectID is the user's Entra object id, the token's "oid"
+// claim.
+func (i *_Identity) GetTokenForTeamsUser(
+	ctx context.Context,
+	teamsToken string,
+	appID string,
+	userObjectID string,
+) (*credential.AccessToken, error) {
+	opts := &teamsUserExchangeOptions{
+		Token:  teamsToken,
+		AppID:  appID,
+		UserID: userObjectID,
+	}
+	if err := opts.isValid(); err != nil {
+		return nil, err
+	}
+	// The exchange has no side effects, so it is safe to retry.
+	res, err := i.client.Do(ctx, &client.Request{
+		Method:     http.MethodPost,
+		Host:       i.host,
+		Path:       "/teamsUser/:exchangeAccessToken",
+		Query:      "api-version=" + apiVersion,
+		Body:       opts,
+		Idempotent: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var response issueAccessTokenResponse
+	if err := res.Decode(&response); err != nil {
+		return nil, err
+	}
+	return &credential.AccessToken{
+		Token:     response.Token,
+		ExpiresOn: response.ExpiresOn,
+	}, nil
+}
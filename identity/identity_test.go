@@ -2,25 +2,227 @@ package identity
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/karim-w/go-azure-communication-services/acstest"
+	"github.com/karim-w/go-azure-communication-services/client"
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	host = ""
-	key  = ""
-)
+func newTestIdentity(t *testing.T) (Identity, *acstest.Server) {
+	srv := acstest.NewServer()
+	t.Cleanup(srv.Close)
+	return New(srv.Host(), srv.Key(), srv.ClientOptions()...), srv
+}
 
 func TestCreateIdentityUser(t *testing.T) {
-	identity := New(host, key)
+	identity, _ := newTestIdentity(t)
 	user, err := identity.CreateIdentity(
 		context.Background(),
 		&CreateIdentityOptions{
-			CreateTokenWithScopes: []string{"chat", "voip"},
+			CreateTokenWithScopes: []TokenScope{ScopeChat, ScopeVoIP},
 			ExpiresInMinutes:      60,
 		},
 	)
 	assert.Nil(t, err)
 	assert.NotNil(t, user)
+	assert.True(t, strings.HasPrefix(user.ID, "8:acs:"))
+	assert.NotEmpty(t, user.Token)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), user.ExpiresOn, time.Minute)
+}
+
+func TestIssueAndRevokeAccessToken(t *testing.T) {
+	identity, srv := newTestIdentity(t)
+	id := srv.CreateIdentity()
+	token, err := identity.IssueAccessToken(
+		context.Background(),
+		id,
+		&IssueTokenOptions{
+			Scopes:           []TokenScope{ScopeChat},
+			ExpiresInMinutes: 60,
+		},
+	)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token.Token)
+	assert.Nil(t, identity.RevokeAccessToken(context.Background(), id))
+	assert.Nil(t, identity.DeleteIdentity(context.Background(), id))
+	err = identity.DeleteIdentity(context.Background(), id)
+	var respErr *client.ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusNotFound, respErr.StatusCode)
+}
+
+func TestWrongKeyIsRejected(t *testing.T) {
+	srv := acstest.NewServer()
+	defer srv.Close()
+	identity := New(srv.Host(), "d3Jvbmc=", srv.ClientOptions()...)
+	_, err := identity.CreateIdentity(
+		context.Background(),
+		&CreateIdentityOptions{
+			CreateTokenWithScopes: []TokenScope{ScopeChat},
+			ExpiresInMinutes:      60,
+		},
+	)
+	assert.True(t, errors.Is(err, client.ERR_UNAUTHORIZED))
+}
+
+func TestCreateIdentityWithHTTPClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/identities", r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"identity":{"id":"8:acs:test"},"accessToken":{"token":"tok","expiresOn":"2030-01-01T00:00:00Z"}}`))
+	}))
+	defer srv.Close()
+	identity := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"",
+		client.WithHTTPClient(srv.Client()),
+	)
+	user, err := identity.CreateIdentity(
+		context.Background(),
+		&CreateIdentityOptions{
+			CreateTokenWithScopes: []TokenScope{ScopeChat},
+			ExpiresInMinutes:      60,
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "8:acs:test", user.ID)
+	assert.Equal(t, "tok", user.Token)
+}
+
+func TestGetTokenForTeamsUser(t *testing.T) {
+	identity, srv := newTestIdentity(t)
+	appID := "11111111-1111-1111-1111-111111111111"
+	userID := "22222222-2222-2222-2222-222222222222"
+	token, err := identity.GetTokenForTeamsUser(
+		context.Background(),
+		srv.TeamsToken(appID, userID, time.Hour),
+		appID,
+		userID,
+	)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token.Token)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresOn, time.Minute)
+
+	_, err = identity.GetTokenForTeamsUser(
+		context.Background(),
+		srv.TeamsToken(appID, userID, time.Hour),
+		"33333333-3333-3333-3333-333333333333",
+		userID,
+	)
+	assert.True(t, client.HasErrorCode(err, "AppIdMismatch"))
+
+	_, err = identity.GetTokenForTeamsUser(
+		context.Background(),
+		srv.TeamsToken(appID, userID, -time.Minute),
+		appID,
+		userID,
+	)
+	assert.True(t, errors.Is(err, client.ERR_UNAUTHORIZED))
+}
+
+func TestGetTokenForTeamsUserValidation(t *testing.T) {
+	identity := New("127.0.0.1:1", "")
+	appID := "11111111-1111-1111-1111-111111111111"
+	cases := []struct {
+		token, appID, userID string
+		want                 error
+	}{
+		{"", appID, appID, ERR_EMPTY_TEAMS_TOKEN},
+		{"token", "app", appID, ERR_INVALID_APP_ID},
+		{"token", appID, "", ERR_INVALID_USER_ID},
+		{"token", appID, "11111111-1111-1111-1111-11111111111g", ERR_INVALID_USER_ID},
+	}
+	for _, c := range cases {
+		_, err := identity.GetTokenForTeamsUser(context.Background(), c.token, c.appID, c.userID)
+		assert.Equal(t, c.want, err)
+	}
+}
+
+func TestCreateIdentityWithCustomID(t *testing.T) {
+	identity, _ := newTestIdentity(t)
+	opts := &CreateIdentityOptions{
+		CreateTokenWithScopes: []TokenScope{ScopeChat},
+		ExpiresInMinutes:      60,
+		CustomID:              "user-42",
+	}
+	first, err := identity.CreateIdentity(context.Background(), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-42", first.CustomID)
+	second, err := identity.CreateIdentity(context.Background(), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.NotEqual(t, first.Token, second.Token)
+}
+
+func TestGetIdentity(t *testing.T) {
+	identity, srv := newTestIdentity(t)
+	id := srv.CreateIdentity()
+	got, err := identity.GetIdentity(context.Background(), id)
+	assert.Nil(t, err)
+	assert.Equal(t, id, got.ID)
+	assert.True(t, got.LastTokenIssuedAt.IsZero())
+
+	srv.IssueToken(id, []string{"chat"}, time.Hour)
+	got, err = identity.GetIdentity(context.Background(), id)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), got.LastTokenIssuedAt, time.Minute)
+
+	_, err = identity.GetIdentity(context.Background(), "8:acs:missing")
+	assert.True(t, client.HasErrorCode(err, "IdentityNotFound"))
+}
+
+func TestCreateIdentityWithoutToken(t *testing.T) {
+	identity, _ := newTestIdentity(t)
+	for _, opts := range []*CreateIdentityOptions{nil, {}, {CustomID: "user-7"}} {
+		user, err := identity.CreateIdentity(context.Background(), opts)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, user.ID)
+		assert.False(t, user.HasToken())
+		assert.True(t, user.ExpiresOn.IsZero())
+	}
+}
+
+func TestScopesAreValidated(t *testing.T) {
+	identity := New("127.0.0.1:1", "")
+	_, err := identity.CreateIdentity(context.Background(), &CreateIdentityOptions{
+		CreateTokenWithScopes: []TokenScope{ScopeChat, "void"},
+		ExpiresInMinutes:      60,
+	})
+	assert.True(t, errors.Is(err, ERR_UNKNOWN_SCOPE))
+	_, err = identity.IssueAccessToken(context.Background(), "8:acs:x", &IssueTokenOptions{
+		Scopes:           []TokenScope{"void"},
+		ExpiresInMinutes: 60,
+	})
+	assert.True(t, errors.Is(err, ERR_UNKNOWN_SCOPE))
+	_, err = identity.IssueAccessToken(context.Background(), "8:acs:x", &IssueTokenOptions{
+		Scopes:           []TokenScope{},
+		ExpiresInMinutes: 60,
+	})
+	assert.Equal(t, ERR_SCOPES_CANNOT_BE_EMPTY, err)
+	_, err = identity.CreateIdentity(context.Background(), &CreateIdentityOptions{
+		ExpiresInMinutes: 60,
+	})
+	assert.Equal(t, ERR_SCOPES_CANNOT_BE_EMPTY, err)
+}
+
+func TestEveryScopeIsAccepted(t *testing.T) {
+	identity, _ := newTestIdentity(t)
+	user, err := identity.CreateIdentity(context.Background(), &CreateIdentityOptions{
+		CreateTokenWithScopes: []TokenScope{
+			ScopeChat,
+			ScopeVoIP,
+			ScopeChatJoin,
+			ScopeChatJoinLimited,
+			ScopeVoIPJoin,
+		},
+		ExpiresInMinutes: 60,
+	})
+	assert.Nil(t, err)
+	assert.True(t, user.HasToken())
 }
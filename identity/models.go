@@ -2,49 +2,85 @@ package identity
 
 import (
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/karim-w/go-azure-communication-services/identifier"
 )
 
 type createIdentityResponse struct {
-	Identity struct {
-		ID string `json:"id"`
-	} `json:"identity"`
-	AccessToken struct {
+	Identity    CommunicationIdentity `json:"identity"`
+	AccessToken *struct {
 		Token     string    `json:"token"`
 		ExpiresOn time.Time `json:"expiresOn"`
 	} `json:"accessToken"`
 }
 
+// CreateIdentityOptions configure CreateIdentity. Without scopes the
+// identity is created without a token.
 type CreateIdentityOptions struct {
-	CreateTokenWithScopes []string `json:"createTokenWithScopes"`
-	ExpiresInMinutes      int      `json:"expiresInMinutes"`
+	CreateTokenWithScopes []TokenScope `json:"createTokenWithScopes,omitempty"`
+	// ExpiresInMinutes is the token lifetime; it must be zero when no
+	// token is issued.
+	ExpiresInMinutes int `json:"expiresInMinutes,omitempty"`
+	// CustomID maps the identity to an id of your own. Creating an
+	// identity with a CustomID that is already in use returns the
+	// existing identity instead of a new one.
+	CustomID string `json:"customId,omitempty"`
 }
 
 type IssueTokenOptions struct {
-	Scopes           []string `json:"scopes"`
-	ExpiresInMinutes int      `json:"expiresInMinutes"`
+	Scopes           []TokenScope `json:"scopes"`
+	ExpiresInMinutes int          `json:"expiresInMinutes"`
 }
 
+// ACSIdentity is an identity with the token issued for it, if any. Token
+// is empty and ExpiresOn zero when no token was requested.
 type ACSIdentity struct {
 	ID        string    `json:"id"`
-	Token     string    `json:"token"`
+	CustomID  string    `json:"customId,omitempty"`
+	Token     string    `json:"token,omitempty"`
 	ExpiresOn time.Time `json:"expiresOn"`
 }
 
-const apiVersion = "2022-10-01"
+// HasToken reports whether a token was issued with the identity.
+func (a *ACSIdentity) HasToken() bool {
+	return a.Token != ""
+}
+
+// CommunicationIdentity is an identity as returned by GetIdentity.
+type CommunicationIdentity struct {
+	ID       string `json:"id"`
+	CustomID string `json:"customId,omitempty"`
+	// LastTokenIssuedAt is zero if no token was ever issued.
+	LastTokenIssuedAt time.Time `json:"lastTokenIssuedAt"`
+}
+
+// apiVersion is the identity API every call uses. Custom ids are only
+// published in the 2025-03-02-preview version.
+const apiVersion = "2025-03-02-preview"
 
 var (
 	ERR_NIL_OPTIONS            = errors.New("options cannot be nil")
 	ERR_SCOPES_CANNOT_BE_EMPTY = errors.New("scopes cannot be empty")
 	ERR_EXPIRY_OUT_OF_RANGE    = errors.New("expiry must be between 60 and 1440 minutes")
+	ERR_EMPTY_TEAMS_TOKEN      = errors.New("teams token cannot be empty")
+	ERR_INVALID_APP_ID         = errors.New("app id must be a GUID")
+	ERR_INVALID_USER_ID        = errors.New("user object id must be a GUID")
 )
 
 func (c *CreateIdentityOptions) isValid() error {
 	if c == nil {
-		return ERR_NIL_OPTIONS
+		return nil
 	}
-	if c.CreateTokenWithScopes == nil {
-		return ERR_SCOPES_CANNOT_BE_EMPTY
+	if len(c.CreateTokenWithScopes) == 0 {
+		if c.ExpiresInMinutes != 0 {
+			return ERR_SCOPES_CANNOT_BE_EMPTY
+		}
+		return nil
+	}
+	if err := validateScopes(c.CreateTokenWithScopes); err != nil {
+		return err
 	}
 	if c.ExpiresInMinutes < 60 || c.ExpiresInMinutes > 1440 {
 		return ERR_EXPIRY_OUT_OF_RANGE
@@ -56,25 +92,68 @@ func (i *IssueTokenOptions) isValid() error {
 	if i == nil {
 		return ERR_NIL_OPTIONS
 	}
-	if i.Scopes == nil {
+	if len(i.Scopes) == 0 {
 		return ERR_SCOPES_CANNOT_BE_EMPTY
 	}
+	if err := validateScopes(i.Scopes); err != nil {
+		return err
+	}
 	if i.ExpiresInMinutes < 60 || i.ExpiresInMinutes > 1440 {
 		return ERR_EXPIRY_OUT_OF_RANGE
 	}
 	return nil
 }
 
+type teamsUserExchangeOptions struct {
+	Token  string `json:"token"`
+	AppID  string `json:"appId"`
+	UserID string `json:"userId"`
+}
+
+func (t *teamsUserExchangeOptions) isValid() error {
+	if t.Token == "" {
+		return ERR_EMPTY_TEAMS_TOKEN
+	}
+	if !isGUID(t.AppID) {
+		return ERR_INVALID_APP_ID
+	}
+	if !isGUID(t.UserID) {
+		return ERR_INVALID_USER_ID
+	}
+	return nil
+}
+
+// isGUID reports whether s has the 8-4-4-4-12 hex digit form of a GUID.
+func isGUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 type issueAccessTokenResponse struct {
 	Token     string    `json:"token"`
 	ExpiresOn time.Time `json:"expiresOn"`
 }
 
-type CommunicationIdentifier struct {
-	RawID             string            `json:"rawId"`
-	CommunicationUser CommunicationUser `json:"communicationUser"`
-}
+// CommunicationUser is an identity created by this package.
+//
+// Deprecated: use identifier.CommunicationUser.
+type CommunicationUser = identifier.CommunicationUser
 
-type CommunicationUser struct {
-	ID string `json:"id"`
+// CommunicationUser returns the identifier of the identity.
+func (a *ACSIdentity) CommunicationUser() identifier.CommunicationUser {
+	return identifier.CommunicationUser{ID: a.ID}
 }
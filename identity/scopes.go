@@ -0,0 +1,45 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TokenScope is a capability an access token grants.
+type TokenScope string
+
+const (
+	// ScopeChat grants full access to chat.
+	ScopeChat TokenScope = "chat"
+	// ScopeVoIP grants full access to calling.
+	ScopeVoIP TokenScope = "voip"
+	// ScopeChatJoin grants access to the chat threads the identity
+	// participates in, without creating threads.
+	ScopeChatJoin TokenScope = "chat.join"
+	// ScopeChatJoinLimited is ScopeChatJoin without adding or removing
+	// participants.
+	ScopeChatJoinLimited TokenScope = "chat.join.limited"
+	// ScopeVoIPJoin grants access to calls the identity is invited to,
+	// without starting new ones.
+	ScopeVoIPJoin TokenScope = "voip.join"
+)
+
+var ERR_UNKNOWN_SCOPE = errors.New("unknown token scope")
+
+// IsValid reports whether s is one of the scopes ACS issues tokens for.
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case ScopeChat, ScopeVoIP, ScopeChatJoin, ScopeChatJoinLimited, ScopeVoIPJoin:
+		return true
+	}
+	return false
+}
+
+func validateScopes(scopes []TokenScope) error {
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return fmt.Errorf("%w: %q", ERR_UNKNOWN_SCOPE, scope)
+		}
+	}
+	return nil
+}
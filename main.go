@@ -28,7 +28,7 @@ func main() {
 	identity, err := identityClient.CreateIdentity(
 		context.Background(),
 		&identity.CreateIdentityOptions{
-			CreateTokenWithScopes: []string{"chat"},
+			CreateTokenWithScopes: []identity.TokenScope{identity.ScopeChat},
 			ExpiresInMinutes:      60,
 		},
 	)
@@ -6,4 +6,5 @@ var (
 	ERR_ROOMS_CREATE_ROOM_INVALID_OPTIONS = errors.New("invalid options")
 	ERR_ROOMS_NIL_OPTIONS                 = errors.New("option is nil")
 	ERR_ROOMS_OPERATION_FAILED            = errors.New("operation failed")
+	ERR_ROOMS_REQUIRES_GA_API_VERSION     = errors.New("operation requires a GA rooms api version")
 )
@@ -0,0 +1,273 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+const (
+	defaultExtendWindow = time.Hour
+	defaultExtension    = 24 * time.Hour
+	defaultGracePeriod  = time.Hour
+	defaultInterval     = time.Minute
+)
+
+// InUseFunc reports whether a room is still in use, for example because a
+// call is running in it.
+type InUseFunc func(ctx context.Context, roomId string) (bool, error)
+
+// ManagerOptions tune NewManager.
+type ManagerOptions struct {
+	// Store keeps the tracked rooms. Defaults to NewMemoryRoomStore.
+	Store RoomStore
+	// InUse is asked whether a room close to expiry should be extended.
+	// Without it rooms are never extended.
+	InUse InUseFunc
+	// ExtendWindow is how long before ValidUntil an in-use room is
+	// extended. Defaults to 1 hour.
+	ExtendWindow time.Duration
+	// Extension is how far from now ValidUntil is moved, capped at
+	// MaxRoomValidity after ValidFrom. Defaults to 24 hours.
+	Extension time.Duration
+	// GracePeriod is how long after ValidUntil an expired room is
+	// deleted. Defaults to 1 hour.
+	GracePeriod time.Duration
+	// Interval is how often Run checks the rooms. Defaults to 1 minute.
+	Interval time.Duration
+	// OnCheck, if set, receives the outcome of every check made by Run.
+	// err is set when a check fails as a whole, for example because
+	// Store.List failed; report is then nil or partial.
+	OnCheck func(report *ManagerReport, err error)
+}
+
+// ManagerReport is the outcome of one Check.
+type ManagerReport struct {
+	Extended []string
+	Deleted  []string
+	// Failed holds the rooms that could not be checked, extended or
+	// deleted; they are retried on the next check.
+	Failed map[string]error
+}
+
+// Manager creates rooms and keeps them alive while they are in use,
+// extending ValidUntil before it passes, then deletes them a grace period
+// after they expire. Rooms that are still valid are never deleted.
+type Manager interface {
+	CreateRoom(
+		ctx context.Context,
+		options *CreateRoomOptions,
+	) (*RoomModel, error)
+	Track(
+		ctx context.Context,
+		room *RoomModel,
+	) error
+	Forget(
+		ctx context.Context,
+		roomId string,
+	) error
+	Check(
+		ctx context.Context,
+	) (*ManagerReport, error)
+	Run(
+		ctx context.Context,
+	) error
+}
+
+type _manager struct {
+	rooms Rooms
+	opts  ManagerOptions
+	now   func() time.Time
+
+	// mu serializes checks so Run and Check never act on a room twice.
+	mu sync.Mutex
+}
+
+// NewManager returns a Manager that manages rooms through client.
+func NewManager(
+	client Rooms,
+	opts *ManagerOptions,
+) Manager {
+	m := &_manager{rooms: client, now: time.Now}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Store == nil {
+		m.opts.Store = NewMemoryRoomStore()
+	}
+	if m.opts.ExtendWindow <= 0 {
+		m.opts.ExtendWindow = defaultExtendWindow
+	}
+	if m.opts.Extension <= 0 {
+		m.opts.Extension = defaultExtension
+	}
+	if m.opts.GracePeriod <= 0 {
+		m.opts.GracePeriod = defaultGracePeriod
+	}
+	if m.opts.Interval <= 0 {
+		m.opts.Interval = defaultInterval
+	}
+	return m
+}
+
+// CreateRoom creates a room and tracks it.
+func (m *_manager) CreateRoom(
+	ctx context.Context,
+	options *CreateRoomOptions,
+) (*RoomModel, error) {
+	room, err := m.rooms.CreateRoom(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Track(ctx, room); err != nil {
+		return room, err
+	}
+	return room, nil
+}
+
+// Track starts managing a room created elsewhere.
+func (m *_manager) Track(
+	ctx context.Context,
+	room *RoomModel,
+) error {
+	if room == nil {
+		return ERR_ROOMS_NIL_OPTIONS
+	}
+	return m.opts.Store.Save(ctx, ManagedRoom{
+		ID:             room.Id,
+		ValidFrom:      room.ValidFrom,
+		ValidUntil:     room.ValidUntil,
+		RoomJoinPolicy: room.RoomJoinPolicy,
+	})
+}
+
+// Forget stops managing a room without deleting it.
+func (m *_manager) Forget(
+	ctx context.Context,
+	roomId string,
+) error {
+	return m.opts.Store.Delete(ctx, roomId)
+}
+
+// Check makes one pass over the tracked rooms: rooms within ExtendWindow
+// of expiry are extended if InUse reports them in use, and rooms that
+// expired more than GracePeriod ago are deleted.
+func (m *_manager) Check(
+	ctx context.Context,
+) (*ManagerReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rooms, err := m.opts.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &ManagerReport{
+		Extended: []string{},
+		Deleted:  []string{},
+		Failed:   map[string]error{},
+	}
+	for _, room := range rooms {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := m.check(ctx, room, report); err != nil {
+			report.Failed[room.ID] = err
+		}
+	}
+	return report, nil
+}
+
+func (m *_manager) check(
+	ctx context.Context,
+	room ManagedRoom,
+	report *ManagerReport,
+) error {
+	now := m.now()
+	if now.Before(room.ValidUntil) {
+		if m.opts.InUse == nil || room.ValidUntil.Sub(now) > m.opts.ExtendWindow {
+			return nil
+		}
+		inUse, err := m.opts.InUse(ctx, room.ID)
+		if err != nil || !inUse {
+			return err
+		}
+		return m.extend(ctx, room, now, report)
+	}
+	if now.Sub(room.ValidUntil) < m.opts.GracePeriod {
+		return nil
+	}
+	err := m.rooms.DeleteRoom(ctx, room.ID)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if err := m.opts.Store.Delete(ctx, room.ID); err != nil {
+		return err
+	}
+	report.Deleted = append(report.Deleted, room.ID)
+	return nil
+}
+
+// extend moves ValidUntil to Extension from now. Rooms already at the
+// maximum validity are left to expire.
+func (m *_manager) extend(
+	ctx context.Context,
+	room ManagedRoom,
+	now time.Time,
+	report *ManagerReport,
+) error {
+	until := now.Add(m.opts.Extension)
+	if limit := room.ValidFrom.Add(MaxRoomValidity); until.After(limit) {
+		until = limit
+	}
+	if !until.After(room.ValidUntil) {
+		return nil
+	}
+	updated, err := m.rooms.UpdateRoom(ctx, room.ID, &UpdateRoomOptions{
+		ValidFrom:      room.ValidFrom,
+		ValidUntil:     until,
+		RoomJoinPolicy: room.RoomJoinPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	room.ValidUntil = until
+	if !updated.ValidUntil.IsZero() {
+		room.ValidUntil = updated.ValidUntil
+	}
+	if err := m.opts.Store.Save(ctx, room); err != nil {
+		return err
+	}
+	report.Extended = append(report.Extended, room.ID)
+	return nil
+}
+
+// Run checks the rooms every Interval until ctx is done, and returns the
+// context's error.
+func (m *_manager) Run(
+	ctx context.Context,
+) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		report, err := m.Check(ctx)
+		if m.opts.OnCheck != nil {
+			m.opts.OnCheck(report, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// isNotFound reports whether err is a 404, such as deleting a room that
+// is already gone.
+func isNotFound(err error) bool {
+	var respErr *client.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,235 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestManager(t *testing.T, client Rooms, opts *ManagerOptions) (*_manager, *testClock) {
+	clock := &testClock{time.Now()}
+	m := NewManager(client, opts).(*_manager)
+	m.now = clock.Now
+	return m, clock
+}
+
+func createManagedRoom(t *testing.T, m Manager, validFor time.Duration) *RoomModel {
+	room, err := m.CreateRoom(context.TODO(), &CreateRoomOptions{
+		ValidFrom:      time.Now(),
+		ValidUntil:     time.Now().Add(validFor),
+		RoomJoinPolicy: INVITE_ONLY,
+	})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return room
+}
+
+func TestManagerExtendsRoomsInUse(t *testing.T) {
+	client, _ := newTestRooms(t)
+	m, clock := newTestManager(t, client, &ManagerOptions{
+		InUse: func(ctx context.Context, roomId string) (bool, error) {
+			return true, nil
+		},
+	})
+	room := createManagedRoom(t, m, 30*time.Minute)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{room.Id}, report.Extended)
+	got, err := client.GetRoom(context.TODO(), room.Id)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, clock.now.Add(defaultExtension), got.ValidUntil, time.Second)
+	report, err = m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Extended)
+}
+
+func TestManagerExtensionIsCapped(t *testing.T) {
+	client, _ := newTestRooms(t)
+	m, clock := newTestManager(t, client, &ManagerOptions{
+		InUse: func(ctx context.Context, roomId string) (bool, error) {
+			return true, nil
+		},
+	})
+	room := createManagedRoom(t, m, MaxRoomValidity-time.Minute)
+	clock.now = room.ValidUntil.Add(-time.Minute)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{room.Id}, report.Extended)
+	got, err := client.GetRoom(context.TODO(), room.Id)
+	assert.Nil(t, err)
+	assert.True(t, room.ValidFrom.Add(MaxRoomValidity).Equal(got.ValidUntil))
+	report, err = m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Extended)
+}
+
+func TestManagerPreservesPstnDialOut(t *testing.T) {
+	client, _ := newGATestRooms(t)
+	m, _ := newTestManager(t, client, &ManagerOptions{
+		InUse: func(ctx context.Context, roomId string) (bool, error) {
+			return true, nil
+		},
+	})
+	room, err := m.CreateRoom(context.TODO(), &CreateRoomOptions{
+		ValidUntil:         time.Now().Add(time.Minute),
+		PstnDialOutEnabled: true,
+	})
+	assert.Nil(t, err)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{room.Id}, report.Extended)
+	got, err := client.GetRoom(context.TODO(), room.Id)
+	assert.Nil(t, err)
+	assert.True(t, got.PstnDialOutEnabled)
+}
+
+func TestManagerDeletesExpiredRoomsAfterGracePeriod(t *testing.T) {
+	client, srv := newTestRooms(t)
+	store := NewMemoryRoomStore()
+	m, clock := newTestManager(t, client, &ManagerOptions{Store: store})
+	room := createManagedRoom(t, m, time.Hour)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Deleted)
+
+	clock.now = room.ValidUntil.Add(defaultGracePeriod - time.Minute)
+	report, err = m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Deleted)
+	assert.Equal(t, []string{room.Id}, srv.Rooms())
+
+	clock.now = room.ValidUntil.Add(defaultGracePeriod)
+	report, err = m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{room.Id}, report.Deleted)
+	assert.Empty(t, srv.Rooms())
+	tracked, _ := store.List(context.TODO())
+	assert.Empty(t, tracked)
+}
+
+func TestManagerKeepsValidRoomsNotInUse(t *testing.T) {
+	client, srv := newTestRooms(t)
+	m, clock := newTestManager(t, client, &ManagerOptions{
+		InUse: func(ctx context.Context, roomId string) (bool, error) {
+			return false, nil
+		},
+	})
+	started := createManagedRoom(t, m, 48*time.Hour)
+	notStarted, err := m.CreateRoom(context.TODO(), &CreateRoomOptions{
+		ValidFrom:      time.Now().Add(24 * time.Hour),
+		ValidUntil:     time.Now().Add(48 * time.Hour),
+		RoomJoinPolicy: INVITE_ONLY,
+	})
+	assert.Nil(t, err)
+	for _, after := range []time.Duration{0, 3 * time.Hour, 47*time.Hour + 30*time.Minute} {
+		clock.now = time.Now().Add(after)
+		report, err := m.Check(context.TODO())
+		assert.Nil(t, err)
+		assert.Empty(t, report.Deleted)
+		assert.Empty(t, report.Extended)
+	}
+	assert.ElementsMatch(t, []string{started.Id, notStarted.Id}, srv.Rooms())
+}
+
+func TestManagerDeletesExpiredRooms(t *testing.T) {
+	client, srv := newTestRooms(t)
+	m, clock := newTestManager(t, client, &ManagerOptions{
+		GracePeriod: time.Minute,
+		InUse: func(ctx context.Context, roomId string) (bool, error) {
+			return true, nil
+		},
+	})
+	room := createManagedRoom(t, m, time.Hour)
+	assert.Nil(t, client.DeleteRoom(context.TODO(), room.Id))
+	clock.now = room.ValidUntil.Add(time.Second)
+	_, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	clock.now = clock.now.Add(time.Minute)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Failed)
+	assert.Equal(t, []string{room.Id}, report.Deleted)
+	assert.Empty(t, srv.Rooms())
+}
+
+func TestManagerReportsFailures(t *testing.T) {
+	client, _ := newTestRooms(t)
+	errBusy := errors.New("busy")
+	m, _ := newTestManager(t, client, &ManagerOptions{
+		InUse: func(ctx context.Context, roomId string) (bool, error) {
+			return false, errBusy
+		},
+	})
+	room := createManagedRoom(t, m, 30*time.Minute)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]error{room.Id: errBusy}, report.Failed)
+}
+
+func TestManagerForget(t *testing.T) {
+	client, srv := newTestRooms(t)
+	store := NewMemoryRoomStore()
+	m, clock := newTestManager(t, client, &ManagerOptions{Store: store})
+	room := createManagedRoom(t, m, time.Hour)
+	assert.Nil(t, m.Forget(context.TODO(), room.Id))
+	clock.now = room.ValidUntil.Add(2 * defaultGracePeriod)
+	report, err := m.Check(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Deleted)
+	assert.Equal(t, []string{room.Id}, srv.Rooms())
+}
+
+func TestManagerRun(t *testing.T) {
+	client, _ := newTestRooms(t)
+	var checks int32
+	ctx, cancel := context.WithCancel(context.TODO())
+	m := NewManager(client, &ManagerOptions{
+		Interval: time.Millisecond,
+		OnCheck: func(report *ManagerReport, err error) {
+			assert.Nil(t, err)
+			if atomic.AddInt32(&checks, 1) == 3 {
+				cancel()
+			}
+		},
+	})
+	assert.Equal(t, context.Canceled, m.Run(ctx))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&checks), int32(3))
+}
+
+type failingRoomStore struct {
+	RoomStore
+	err error
+}
+
+func (s failingRoomStore) List(ctx context.Context) ([]ManagedRoom, error) {
+	return nil, s.err
+}
+
+func TestManagerRunReportsCheckErrors(t *testing.T) {
+	client, _ := newTestRooms(t)
+	errStore := errors.New("store unavailable")
+	ctx, cancel := context.WithCancel(context.TODO())
+	var got error
+	m := NewManager(client, &ManagerOptions{
+		Store:    failingRoomStore{NewMemoryRoomStore(), errStore},
+		Interval: time.Millisecond,
+		OnCheck: func(report *ManagerReport, err error) {
+			assert.Nil(t, report)
+			got = err
+			cancel()
+		},
+	})
+	assert.Equal(t, context.Canceled, m.Run(ctx))
+	assert.Equal(t, errStore, got)
+}
@@ -1,21 +1,41 @@
 package rooms
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/karim-w/go-azure-communication-services/identifier"
+)
+
+// APIVersion selects the rooms REST API a client talks to.
+type APIVersion string
 
 const (
-	apiVersion = "2022-02-01"
+	// APIVersion2022_02_01 is the preview API with a room join policy and
+	// separate participants:add, :update and :remove endpoints. It is the
+	// default so existing callers keep working.
+	APIVersion2022_02_01 APIVersion = "2022-02-01"
+	// APIVersion2024_04_15 is the GA API. Participants are upserted and
+	// removed with a single merge-patch, rooms have no join policy and
+	// PSTN dial-out can be enabled.
+	APIVersion2024_04_15 APIVersion = "2024-04-15"
+
+	defaultAPIVersion = APIVersion2022_02_01
 )
 
+// isGA reports whether v is a GA version, the first of which was
+// 2023-06-14.
+func (v APIVersion) isGA() bool {
+	return v >= "2023-06-14"
+}
+
 func CreateRoomParticipant(
 	id string,
 	role Role,
 ) RoomParticipant {
 	return RoomParticipant{
-		Role: role,
-		CommunicationIdentifier: CommunicationIdentifier{
-			RawID: id,
-			Id:    id,
-		},
+		Role:                    role,
+		CommunicationIdentifier: identifier.FromRawID(id),
 	}
 }
 
@@ -23,10 +43,7 @@ func RemoveRoomParticipant(
 	id string,
 ) RoomParticipant {
 	return RoomParticipant{
-		CommunicationIdentifier: CommunicationIdentifier{
-			RawID: id,
-			Id:    id,
-		},
+		CommunicationIdentifier: identifier.FromRawID(id),
 	}
 }
 
@@ -39,6 +56,7 @@ const (
 	PRESENTER                  Role           = "Presenter"
 	ATTENDEE                   Role           = "Attendee"
 	CONSUMER                   Role           = "Consumer"
+	COLLABORATOR               Role           = "Collaborator"
 	INVITE_ONLY                RoomJoinPolicy = "InviteOnly"
 	COMMUNICATION_SERVICE_USER RoomJoinPolicy = "communicationServiceUsers"
 )
@@ -48,55 +66,136 @@ type CreateRoomOptions struct {
 	ValidUntil     time.Time         `json:"validUntil"`
 	RoomJoinPolicy RoomJoinPolicy    `json:"roomJoinPolicy"`
 	Participants   []RoomParticipant `json:"participants,omitempty"`
+	// PstnDialOutEnabled lets participants dial out to phone numbers. It
+	// is only sent with a GA API version.
+	PstnDialOutEnabled bool `json:"-"`
 }
 
-func (c *CreateRoomOptions) isValid() bool {
-	if c.ValidFrom.IsZero() || c.ValidUntil.IsZero() {
-		return false
-	}
-	if c.ValidFrom.After(c.ValidUntil) {
-		return false
-	}
-	if c.RoomJoinPolicy == "" {
-		return false
-	}
-	return true
+// RoomModel is a room as returned by the service. The GA API reports
+// CreatedAt and PstnDialOutEnabled and returns no RoomJoinPolicy or
+// Participants; CreatedAt and CreatedDateTime are always both set.
+type RoomModel struct {
+	Id                 string            `json:"id,omitempty"`
+	CreatedDateTime    time.Time         `json:"createdDateTime,omitempty"`
+	CreatedAt          time.Time         `json:"createdAt,omitempty"`
+	ValidFrom          time.Time         `json:"validFrom,omitempty"`
+	ValidUntil         time.Time         `json:"validUntil,omitempty"`
+	RoomJoinPolicy     RoomJoinPolicy    `json:"roomJoinPolicy,omitempty"`
+	PstnDialOutEnabled bool              `json:"pstnDialOutEnabled,omitempty"`
+	Participants       []RoomParticipant `json:"participants,omitempty"`
 }
 
-type RoomModel struct {
-	Id              string            `json:"id,omitempty"`
-	CreatedDateTime time.Time         `json:"createdDateTime,omitempty"`
-	ValidFrom       time.Time         `json:"validFrom,omitempty"`
-	ValidUntil      time.Time         `json:"validUntil,omitempty"`
-	RoomJoinPolicy  RoomJoinPolicy    `json:"roomJoinPolicy,omitempty"`
-	Participants    []RoomParticipant `json:"participants,omitempty"`
+func (r *RoomModel) normalize() *RoomModel {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = r.CreatedDateTime
+	}
+	if r.CreatedDateTime.IsZero() {
+		r.CreatedDateTime = r.CreatedAt
+	}
+	return r
 }
 
 type RoomParticipant struct {
-	CommunicationIdentifier CommunicationIdentifier `json:"communicationIdentifier"`
-	Role                    Role                    `json:"role,omitempty"`
+	CommunicationIdentifier identifier.Identifier `json:"communicationIdentifier"`
+	Role                    Role                  `json:"role,omitempty"`
 }
 
 type roomParticipantsUpdate struct {
 	Participants []RoomParticipant `json:"participants"`
 }
 
-type CommunicationIdentifier struct {
+// roomRequest is the GA body of CreateRoom and UpdateRoom.
+type roomRequest struct {
+	ValidFrom          *time.Time                        `json:"validFrom,omitempty"`
+	ValidUntil         *time.Time                        `json:"validUntil,omitempty"`
+	PstnDialOutEnabled *bool                             `json:"pstnDialOutEnabled,omitempty"`
+	Participants       map[string]*participantProperties `json:"participants,omitempty"`
+}
+
+func newRoomRequest(options *CreateRoomOptions) roomRequest {
+	req := roomRequest{PstnDialOutEnabled: &options.PstnDialOutEnabled}
+	if !options.ValidFrom.IsZero() {
+		req.ValidFrom = &options.ValidFrom
+	}
+	if !options.ValidUntil.IsZero() {
+		req.ValidUntil = &options.ValidUntil
+	}
+	if len(options.Participants) > 0 {
+		req.Participants = participantsPatch(options.Participants, false)
+	}
+	return req
+}
+
+type participantProperties struct {
+	Role Role `json:"role,omitempty"`
+}
+
+// participantsPatch maps participants by raw id for the GA merge-patch. A
+// nil entry, sent as null, removes the participant.
+func participantsPatch(
+	participants []RoomParticipant,
+	remove bool,
+) map[string]*participantProperties {
+	patch := map[string]*participantProperties{}
+	for _, p := range participants {
+		if remove {
+			patch[p.CommunicationIdentifier.RawID()] = nil
+		} else {
+			patch[p.CommunicationIdentifier.RawID()] = &participantProperties{p.Role}
+		}
+	}
+	return patch
+}
+
+type participantsPatchRequest struct {
+	Participants map[string]*participantProperties `json:"participants"`
+}
+
+// participant is a room participant as listed by the GA API.
+type participant struct {
 	RawID string `json:"rawId"`
-	Id    string `json:"id"`
+	Role  Role   `json:"role"`
 }
 
-type UpdateRoomOptions CreateRoomOptions
+// UpdateRoomOptions configure UpdateRoom. On a GA API version the update is
+// a merge-patch: zero times and a nil PstnDialOutEnabled leave the room's
+// values unchanged.
+type UpdateRoomOptions struct {
+	ValidFrom      time.Time         `json:"validFrom"`
+	ValidUntil     time.Time         `json:"validUntil"`
+	RoomJoinPolicy RoomJoinPolicy    `json:"roomJoinPolicy"`
+	Participants   []RoomParticipant `json:"participants,omitempty"`
+	// PstnDialOutEnabled turns PSTN dial-out on or off when set. It is
+	// only sent with a GA API version.
+	PstnDialOutEnabled *bool `json:"-"`
+}
 
-func (c *UpdateRoomOptions) isValid() bool {
-	if c.ValidFrom.IsZero() || c.ValidUntil.IsZero() {
-		return false
+// MarshalJSON leaves out the fields that are not set, so a preview update
+// only changes the ones it names.
+func (u UpdateRoomOptions) MarshalJSON() ([]byte, error) {
+	body := struct {
+		ValidFrom      *time.Time        `json:"validFrom,omitempty"`
+		ValidUntil     *time.Time        `json:"validUntil,omitempty"`
+		RoomJoinPolicy RoomJoinPolicy    `json:"roomJoinPolicy,omitempty"`
+		Participants   []RoomParticipant `json:"participants,omitempty"`
+	}{
+		RoomJoinPolicy: u.RoomJoinPolicy,
+		Participants:   u.Participants,
+	}
+	if !u.ValidFrom.IsZero() {
+		body.ValidFrom = &u.ValidFrom
 	}
-	if c.ValidFrom.After(c.ValidUntil) {
-		return false
+	if !u.ValidUntil.IsZero() {
+		body.ValidUntil = &u.ValidUntil
 	}
-	if c.RoomJoinPolicy == "" {
-		return false
+	return json.Marshal(body)
+}
+
+func (u *UpdateRoomOptions) createOptions() *CreateRoomOptions {
+	return &CreateRoomOptions{
+		ValidFrom:      u.ValidFrom,
+		ValidUntil:     u.ValidUntil,
+		RoomJoinPolicy: u.RoomJoinPolicy,
+		Participants:   u.Participants,
 	}
-	return true
 }
@@ -0,0 +1,119 @@
+package rooms
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+// listAPIVersion is the rooms API rooms are listed with on a preview
+// client. The preview API has no list endpoint, so the first GA version is
+// used instead.
+const listAPIVersion APIVersion = "2023-06-14"
+
+// ListRoomsOptions configure NewListRoomsPager.
+type ListRoomsOptions struct {
+	// MaxPageSize caps the number of rooms per page; zero uses the
+	// service default.
+	MaxPageSize int
+}
+
+func (o *ListRoomsOptions) query(version APIVersion) string {
+	q := url.Values{"api-version": {string(version)}}
+	if o.MaxPageSize > 0 {
+		q.Set("maxPageSize", strconv.Itoa(o.MaxPageSize))
+	}
+	return q.Encode()
+}
+
+// RoomsPager walks the rooms of the resource. Every page after the first
+// one is fetched from the nextLink returned by the service, signed by the
+// client that created the pager.
+type RoomsPager struct {
+	c    *_RoomsClient
+	link client.PageLink
+}
+
+type roomsPage struct {
+	NextLink string      `json:"nextLink"`
+	Value    []RoomModel `json:"value"`
+}
+
+// NewListRoomsPager returns a pager over every room of the resource. No
+// request is made until the first NextPage. Rooms are listed with the
+// client's API version when it is GA and with listAPIVersion otherwise.
+func (c *_RoomsClient) NewListRoomsPager(
+	opts *ListRoomsOptions,
+) *RoomsPager {
+	if opts == nil {
+		opts = &ListRoomsOptions{}
+	}
+	version := c.version
+	if !version.isGA() {
+		version = listAPIVersion
+	}
+	defaults := url.Values{"api-version": {string(version)}}
+	if opts.MaxPageSize > 0 {
+		defaults.Set("maxPageSize", strconv.Itoa(opts.MaxPageSize))
+	}
+	return &RoomsPager{
+		c: c,
+		link: client.PageLink{
+			Resource: "/rooms",
+			Query:    opts.query(version),
+			Defaults: defaults,
+		},
+	}
+}
+
+// More reports whether another page can be fetched.
+func (p *RoomsPager) More() bool {
+	return !p.link.Done
+}
+
+// NextPage fetches the next page of rooms. It returns an empty page once
+// every room has been returned.
+func (p *RoomsPager) NextPage(ctx context.Context) ([]RoomModel, error) {
+	if p.link.Done {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response := roomsPage{}
+	err := p.c.client.Get(
+		ctx,
+		p.c.host,
+		p.link.Resource,
+		p.link.Query,
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.link.Follow(p.c.host, response.NextLink); err != nil {
+		return nil, err
+	}
+	if response.Value == nil {
+		response.Value = []RoomModel{}
+	}
+	for i := range response.Value {
+		response.Value[i].normalize()
+	}
+	return response.Value, nil
+}
+
+// All fetches every remaining page and returns the concatenated rooms.
+func (p *RoomsPager) All(ctx context.Context) ([]RoomModel, error) {
+	rooms := []RoomModel{}
+	for p.More() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return rooms, err
+		}
+		rooms = append(rooms, page...)
+	}
+	return rooms, nil
+}
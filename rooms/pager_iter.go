@@ -0,0 +1,17 @@
+//go:build go1.23
+
+package rooms
+
+import (
+	"context"
+	"iter"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+// Items returns an iterator over every remaining room, fetching pages
+// lazily. Iteration stops after the first error, which is yielded with a
+// zero RoomModel.
+func (p *RoomsPager) Items(ctx context.Context) iter.Seq2[RoomModel, error] {
+	return client.PageItems[RoomModel](ctx, p)
+}
@@ -0,0 +1,27 @@
+//go:build go1.23
+
+package rooms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoomsPagerItems(t *testing.T) {
+	client, _ := newTestRooms(t)
+	ids := []string{}
+	for i := 0; i < 4; i++ {
+		ids = append(ids, createTestRoom(t, client).Id)
+	}
+	listed := []string{}
+	for room, err := range client.NewListRoomsPager(&ListRoomsOptions{MaxPageSize: 2}).Items(context.TODO()) {
+		assert.Nil(t, err)
+		listed = append(listed, room.Id)
+		if len(listed) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, ids[:3], listed)
+}
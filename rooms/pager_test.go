@@ -0,0 +1,95 @@
+package rooms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/karim-w/go-azure-communication-services/acstest"
+	acsclient "github.com/karim-w/go-azure-communication-services/client"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListRooms(t *testing.T) {
+	client, _ := newTestRooms(t)
+	ids := []string{}
+	for i := 0; i < 5; i++ {
+		ids = append(ids, createTestRoom(t, client).Id)
+	}
+	pager := client.NewListRoomsPager(&ListRoomsOptions{MaxPageSize: 2})
+	pages := 0
+	listed := []string{}
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(page), 2)
+		for _, room := range page {
+			listed = append(listed, room.Id)
+		}
+		pages++
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, ids, listed)
+}
+
+func TestListRoomsAll(t *testing.T) {
+	client, _ := newTestRooms(t)
+	rooms, err := client.NewListRoomsPager(nil).All(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, rooms)
+	created := createTestRoom(t, client)
+	pager := client.NewListRoomsPager(&ListRoomsOptions{MaxPageSize: 1})
+	rooms, err = pager.All(context.TODO())
+	assert.Nil(t, err)
+	assert.Len(t, rooms, 1)
+	assert.Equal(t, created.Id, rooms[0].Id)
+	assert.False(t, pager.More())
+}
+
+func TestListRoomsUnderEndpointBasePath(t *testing.T) {
+	srv := acstest.NewServerWithOptions(&acstest.ServerOptions{BasePath: "/acs"})
+	t.Cleanup(srv.Close)
+	client := New(srv.Endpoint(), srv.Key(), srv.ClientOptions()...)
+	for i := 0; i < 3; i++ {
+		createTestRoom(t, client)
+	}
+	rooms, err := client.NewListRoomsPager(&ListRoomsOptions{MaxPageSize: 2}).All(context.TODO())
+	assert.Nil(t, err)
+	assert.Len(t, rooms, 3)
+}
+
+func TestListRoomsIsLazyAndUsesGAVersion(t *testing.T) {
+	requests := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/rooms", r.URL.Path)
+		assert.Equal(t, string(listAPIVersion), r.URL.Query().Get("api-version"))
+		w.Write([]byte(`{"value":[{"id":"room","createdAt":"2024-01-02T03:04:05Z"}]}`))
+	}))
+	defer srv.Close()
+	client := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"c2VjcmV0",
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	pager := client.NewListRoomsPager(nil)
+	assert.Equal(t, 0, requests)
+	rooms, err := pager.All(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Len(t, rooms, 1)
+	assert.Equal(t, 2024, rooms[0].CreatedDateTime.Year())
+}
+
+func TestListRoomsUsesGAClientVersion(t *testing.T) {
+	client, _ := newGATestRooms(t)
+	created := createTestRoom(t, client)
+	rooms, err := client.NewListRoomsPager(nil).All(context.TODO())
+	assert.Nil(t, err)
+	assert.Len(t, rooms, 1)
+	assert.Equal(t, created.Id, rooms[0].Id)
+	assert.Equal(t, rooms[0].CreatedAt, rooms[0].CreatedDateTime)
+}
@@ -2,10 +2,16 @@ package rooms
 
 import (
 	"context"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/karim-w/go-azure-communication-services/client"
+	"github.com/karim-w/go-azure-communication-services/identifier"
 )
 
+const mergePatchContentType = "application/merge-patch+json"
+
 type Rooms interface {
 	CreateRoom(
 		ctx context.Context,
@@ -43,19 +49,75 @@ type Rooms interface {
 		roomId string,
 		Participants ...RoomParticipant,
 	) (*[]RoomParticipant, error)
+	NewListRoomsPager(
+		opts *ListRoomsOptions,
+	) *RoomsPager
+	UpsertParticipants(
+		ctx context.Context,
+		roomId string,
+		Participants ...RoomParticipant,
+	) error
+	WithAPIVersion(
+		version APIVersion,
+	) Rooms
+	SyncParticipants(
+		ctx context.Context,
+		roomId string,
+		desired []RoomParticipant,
+	) (*SyncReport, error)
 }
 
 type _RoomsClient struct {
-	host   string
-	client *client.Client
+	host    string
+	client  *client.Client
+	version APIVersion
 }
 
+// New creates a rooms client signing requests with key. host is either
+// the resource host or a full endpoint URL such as
+// "http://localhost:8080/acs"; see client.ParseEndpoint.
 func New(
 	host string,
 	key string,
+	opts ...client.Option,
+) Rooms {
+	client := client.New(key, opts...)
+	return &_RoomsClient{host, client, defaultAPIVersion}
+}
+
+// NewWithCredential creates a rooms client that authorizes requests with
+// cred, such as a Microsoft Entra ID credential.
+func NewWithCredential(
+	host string,
+	cred client.Credential,
+	opts ...client.Option,
+) Rooms {
+	return &_RoomsClient{host, client.NewWithCredential(cred, opts...), defaultAPIVersion}
+}
+
+// NewFromConnectionString creates a rooms client from an ACS connection
+// string.
+func NewFromConnectionString(
+	connectionString string,
+	opts ...client.Option,
+) (Rooms, error) {
+	cs, err := client.ParseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	return New(cs.Endpoint, cs.AccessKey, opts...), nil
+}
+
+// WithAPIVersion returns a client that talks to the given rooms API
+// version, sharing this client's host and credential.
+func (c *_RoomsClient) WithAPIVersion(
+	version APIVersion,
 ) Rooms {
-	client := client.New(key)
-	return &_RoomsClient{host, client}
+	return &_RoomsClient{c.host, c.client, version}
+}
+
+func (c *_RoomsClient) query() string {
+	return "api-version=" + string(c.version)
 }
 
 func (c *_RoomsClient) CreateRoom(
@@ -65,19 +127,29 @@ func (c *_RoomsClient) CreateRoom(
 	if options == nil {
 		return nil, ERR_ROOMS_NIL_OPTIONS
 	}
-	responseModel := &RoomModel{}
-	err := c.client.Post(
-		ctx,
-		c.host,
-		"/rooms",
-		"api-version="+apiVersion,
-		options,
-		&responseModel,
-	)
+	if err := options.validate(c.version, time.Now(), true); err != nil {
+		return nil, err
+	}
+	var body interface{} = options
+	if c.version.isGA() {
+		body = newRoomRequest(options)
+	}
+	res, err := c.client.Do(ctx, &client.Request{
+		Method:     http.MethodPost,
+		Host:       c.host,
+		Path:       "/rooms",
+		Query:      c.query(),
+		Body:       body,
+		Repeatable: true,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return responseModel, nil
+	responseModel := &RoomModel{}
+	if err := res.Decode(responseModel); err != nil {
+		return nil, err
+	}
+	return responseModel.normalize(), nil
 }
 
 func (c *_RoomsClient) GetRoom(
@@ -88,14 +160,14 @@ func (c *_RoomsClient) GetRoom(
 	err := c.client.Get(
 		ctx,
 		c.host,
-		"/rooms/"+roomId,
-		"api-version="+apiVersion,
+		"/rooms/"+url.PathEscape(roomId),
+		c.query(),
 		&responseModel,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return responseModel, nil
+	return responseModel.normalize(), nil
 }
 
 func (c *_RoomsClient) UpdateRoom(
@@ -106,24 +178,64 @@ func (c *_RoomsClient) UpdateRoom(
 	if options == nil {
 		return nil, ERR_ROOMS_NIL_OPTIONS
 	}
+	if err := options.createOptions().validate(c.version, time.Now(), false); err != nil {
+		return nil, err
+	}
+	if c.version.isGA() {
+		return c.patchRoom(ctx, roomId, options)
+	}
 	responseModel := &RoomModel{}
 	err := c.client.Patch(
 		ctx,
 		c.host,
-		"/rooms/"+roomId,
-		"api-version="+apiVersion,
-		RoomModel{
-			ValidFrom:      options.ValidFrom,
-			ValidUntil:     options.ValidUntil,
-			Participants:   options.Participants,
-			RoomJoinPolicy: options.RoomJoinPolicy,
-		},
+		"/rooms/"+url.PathEscape(roomId),
+		c.query(),
+		options,
 		&responseModel,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return responseModel, nil
+	return responseModel.normalize(), nil
+}
+
+// patchRoom updates a room with a GA merge-patch. Participants are not
+// part of the room resource and are upserted separately, so the update can
+// be partly applied: when the upsert fails the room has already changed
+// and is returned together with the error.
+func (c *_RoomsClient) patchRoom(
+	ctx context.Context,
+	roomId string,
+	options *UpdateRoomOptions,
+) (*RoomModel, error) {
+	req := roomRequest{PstnDialOutEnabled: options.PstnDialOutEnabled}
+	if !options.ValidFrom.IsZero() {
+		req.ValidFrom = &options.ValidFrom
+	}
+	if !options.ValidUntil.IsZero() {
+		req.ValidUntil = &options.ValidUntil
+	}
+	res, err := c.client.Do(ctx, &client.Request{
+		Method: http.MethodPatch,
+		Host:   c.host,
+		Path:   "/rooms/" + url.PathEscape(roomId),
+		Query:  c.query(),
+		Header: http.Header{"Content-Type": {mergePatchContentType}},
+		Body:   req,
+	})
+	if err != nil {
+		return nil, err
+	}
+	responseModel := &RoomModel{}
+	if err := res.Decode(responseModel); err != nil {
+		return nil, err
+	}
+	if len(options.Participants) > 0 {
+		if err := c.UpsertParticipants(ctx, roomId, options.Participants...); err != nil {
+			return responseModel.normalize(), err
+		}
+	}
+	return responseModel.normalize(), nil
 }
 
 func (c *_RoomsClient) DeleteRoom(
@@ -133,8 +245,8 @@ func (c *_RoomsClient) DeleteRoom(
 	return c.client.Delete(
 		ctx,
 		c.host,
-		"/rooms/"+roomId,
-		"api-version="+apiVersion,
+		"/rooms/"+url.PathEscape(roomId),
+		c.query(),
 		nil,
 	)
 }
@@ -144,12 +256,18 @@ func (c *_RoomsClient) AddParticipants(
 	roomId string,
 	Participants ...RoomParticipant,
 ) (*[]RoomParticipant, error) {
+	if c.version.isGA() {
+		if err := c.UpsertParticipants(ctx, roomId, Participants...); err != nil {
+			return nil, err
+		}
+		return c.GetParticipants(ctx, roomId)
+	}
 	responseModel := &roomParticipantsUpdate{}
 	err := c.client.Post(
 		ctx,
 		c.host,
-		"/rooms/"+roomId+"/participants:add",
-		"api-version="+apiVersion,
+		"/rooms/"+url.PathEscape(roomId)+"/participants:add",
+		c.query(),
 		roomParticipantsUpdate{Participants},
 		&responseModel,
 	)
@@ -163,12 +281,15 @@ func (c *_RoomsClient) GetParticipants(
 	ctx context.Context,
 	roomId string,
 ) (*[]RoomParticipant, error) {
+	if c.version.isGA() {
+		return c.listParticipants(ctx, roomId)
+	}
 	responseModel := &roomParticipantsUpdate{}
 	err := c.client.Get(
 		ctx,
 		c.host,
-		"/rooms/"+roomId+"/participants",
-		"api-version="+apiVersion,
+		"/rooms/"+url.PathEscape(roomId)+"/participants",
+		c.query(),
 		&responseModel,
 	)
 	if err != nil {
@@ -182,12 +303,18 @@ func (c *_RoomsClient) UpdateParticipants(
 	roomId string,
 	Participants ...RoomParticipant,
 ) (*[]RoomParticipant, error) {
+	if c.version.isGA() {
+		if err := c.UpsertParticipants(ctx, roomId, Participants...); err != nil {
+			return nil, err
+		}
+		return c.GetParticipants(ctx, roomId)
+	}
 	responseModel := &roomParticipantsUpdate{}
 	err := c.client.Post(
 		ctx,
 		c.host,
-		"/rooms/"+roomId+"/participants:update",
-		"api-version="+apiVersion,
+		"/rooms/"+url.PathEscape(roomId)+"/participants:update",
+		c.query(),
 		roomParticipantsUpdate{Participants},
 		&responseModel,
 	)
@@ -202,12 +329,19 @@ func (c *_RoomsClient) RemoveParticipants(
 	roomId string,
 	Participants ...RoomParticipant,
 ) (*[]RoomParticipant, error) {
+	if c.version.isGA() {
+		err := c.patchParticipants(ctx, roomId, participantsPatch(Participants, true))
+		if err != nil {
+			return nil, err
+		}
+		return c.GetParticipants(ctx, roomId)
+	}
 	responseModel := &roomParticipantsUpdate{}
 	err := c.client.Post(
 		ctx,
 		c.host,
-		"/rooms/"+roomId+"/participants:remove",
-		"api-version="+apiVersion,
+		"/rooms/"+url.PathEscape(roomId)+"/participants:remove",
+		c.query(),
 		roomParticipantsUpdate{Participants},
 		&responseModel,
 	)
@@ -216,3 +350,71 @@ func (c *_RoomsClient) RemoveParticipants(
 	}
 	return &responseModel.Participants, nil
 }
+
+// UpsertParticipants adds participants to a room or changes their role
+// with a single merge-patch. It requires a GA API version.
+func (c *_RoomsClient) UpsertParticipants(
+	ctx context.Context,
+	roomId string,
+	Participants ...RoomParticipant,
+) error {
+	if !c.version.isGA() {
+		return ERR_ROOMS_REQUIRES_GA_API_VERSION
+	}
+	return c.patchParticipants(ctx, roomId, participantsPatch(Participants, false))
+}
+
+func (c *_RoomsClient) patchParticipants(
+	ctx context.Context,
+	roomId string,
+	patch map[string]*participantProperties,
+) error {
+	_, err := c.client.Do(ctx, &client.Request{
+		Method: http.MethodPatch,
+		Host:   c.host,
+		Path:   "/rooms/" + url.PathEscape(roomId) + "/participants",
+		Query:  c.query(),
+		Header: http.Header{"Content-Type": {mergePatchContentType}},
+		Body:   participantsPatchRequest{patch},
+	})
+	return err
+}
+
+// listParticipants follows every page of the GA participants list.
+func (c *_RoomsClient) listParticipants(
+	ctx context.Context,
+	roomId string,
+) (*[]RoomParticipant, error) {
+	link := &client.PageLink{
+		Resource: "/rooms/" + url.PathEscape(roomId) + "/participants",
+		Query:    c.query(),
+		Defaults: url.Values{"api-version": {string(c.version)}},
+	}
+	participants := []RoomParticipant{}
+	for !link.Done {
+		response := struct {
+			NextLink string        `json:"nextLink"`
+			Value    []participant `json:"value"`
+		}{}
+		err := c.client.Get(
+			ctx,
+			c.host,
+			link.Resource,
+			link.Query,
+			&response,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range response.Value {
+			participants = append(participants, RoomParticipant{
+				CommunicationIdentifier: identifier.FromRawID(p.RawID),
+				Role:                    p.Role,
+			})
+		}
+		if err := link.Follow(c.host, response.NextLink); err != nil {
+			return nil, err
+		}
+	}
+	return &participants, nil
+}
@@ -2,21 +2,44 @@ package rooms
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/karim-w/go-azure-communication-services/acstest"
+	acsclient "github.com/karim-w/go-azure-communication-services/client"
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	host   = ""
-	key    = ""
-	id     = ""
-	roomid = ""
-)
+func newTestRooms(t *testing.T) (Rooms, *acstest.Server) {
+	srv := acstest.NewServer()
+	t.Cleanup(srv.Close)
+	return New(srv.Host(), srv.Key(), srv.ClientOptions()...), srv
+}
+
+func createTestRoom(t *testing.T, client Rooms, participants ...RoomParticipant) *RoomModel {
+	room, err := client.CreateRoom(
+		context.TODO(),
+		&CreateRoomOptions{
+			ValidFrom:      time.Now(),
+			ValidUntil:     time.Now().Add(time.Hour),
+			RoomJoinPolicy: INVITE_ONLY,
+			Participants:   participants,
+		},
+	)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return room
+}
 
 func TestCreateRoom(t *testing.T) {
-	client := New(host, key)
+	client, srv := newTestRooms(t)
+	id := srv.CreateIdentity()
 	room, err := client.CreateRoom(
 		context.TODO(),
 		&CreateRoomOptions{
@@ -30,26 +53,45 @@ func TestCreateRoom(t *testing.T) {
 	)
 	assert.Nil(t, err)
 	assert.NotNil(t, room)
+	assert.NotEmpty(t, room.Id)
+	assert.Equal(t, []string{room.Id}, srv.Rooms())
+	assert.Len(t, room.Participants, 1)
+	assert.Equal(t, PRESENTER, room.Participants[0].Role)
 }
 
 func TestGetRoom(t *testing.T) {
-	client := New(host, key)
+	client, _ := newTestRooms(t)
+	created := createTestRoom(t, client)
 	room, err := client.GetRoom(
 		context.TODO(),
-		roomid,
+		created.Id,
 	)
 	assert.Nil(t, err)
 	assert.NotNil(t, room)
+	assert.Equal(t, created.Id, room.Id)
+	assert.Equal(t, INVITE_ONLY, room.RoomJoinPolicy)
+}
+
+func TestGetMissingRoom(t *testing.T) {
+	client, _ := newTestRooms(t)
+	room, err := client.GetRoom(context.TODO(), "missing")
+	assert.Nil(t, room)
+	var respErr *acsclient.ResponseError
+	assert.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusNotFound, respErr.StatusCode)
 }
 
 func TestUpdateRoom(t *testing.T) {
-	client := New(host, key)
+	client, srv := newTestRooms(t)
+	id := srv.CreateIdentity()
+	created := createTestRoom(t, client)
+	validUntil := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
 	room, err := client.UpdateRoom(
 		context.TODO(),
-		roomid,
+		created.Id,
 		&UpdateRoomOptions{
 			ValidFrom:      time.Now(),
-			ValidUntil:     time.Now().Add(time.Hour),
+			ValidUntil:     validUntil,
 			RoomJoinPolicy: INVITE_ONLY,
 			Participants: []RoomParticipant{
 				CreateRoomParticipant(id, PRESENTER),
@@ -58,56 +100,353 @@ func TestUpdateRoom(t *testing.T) {
 	)
 	assert.Nil(t, err)
 	assert.NotNil(t, room)
+	assert.True(t, validUntil.Equal(room.ValidUntil))
+	assert.Len(t, room.Participants, 1)
+}
+
+func TestUpdateRoomSendsOnlySetFields(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"roomJoinPolicy":"communicationServiceUsers"}`, string(body))
+		w.Write([]byte(`{"id":"room","roomJoinPolicy":"communicationServiceUsers"}`))
+	}))
+	defer srv.Close()
+	client := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"c2VjcmV0",
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	room, err := client.UpdateRoom(context.TODO(), "room", &UpdateRoomOptions{
+		RoomJoinPolicy: COMMUNICATION_SERVICE_USER,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, COMMUNICATION_SERVICE_USER, room.RoomJoinPolicy)
 }
 
 func TestDeleteRoom(t *testing.T) {
-	client := New(host, key)
+	client, srv := newTestRooms(t)
+	room := createTestRoom(t, client)
 	err := client.DeleteRoom(
 		context.TODO(),
-		roomid,
+		room.Id,
 	)
 	assert.Nil(t, err)
+	assert.Empty(t, srv.Rooms())
 }
 
 func TestAddParticipant(t *testing.T) {
-	client := New(host, key)
-	room, err := client.AddParticipants(
+	client, srv := newTestRooms(t)
+	id := srv.CreateIdentity()
+	room := createTestRoom(t, client)
+	participants, err := client.AddParticipants(
 		context.TODO(),
-		roomid,
+		room.Id,
 		CreateRoomParticipant(id, PRESENTER),
 	)
 	assert.Nil(t, err)
-	assert.NotNil(t, room)
+	assert.NotNil(t, participants)
+	assert.Len(t, *participants, 1)
+	assert.Equal(t, id, (*participants)[0].CommunicationIdentifier.RawID())
 }
 
 func TestGetParticipants(t *testing.T) {
-	client := New(host, key)
-	room, err := client.GetParticipants(
+	client, srv := newTestRooms(t)
+	id := srv.CreateIdentity()
+	room := createTestRoom(t, client, CreateRoomParticipant(id, ATTENDEE))
+	participants, err := client.GetParticipants(
 		context.TODO(),
-		roomid,
+		room.Id,
 	)
 	assert.Nil(t, err)
-	assert.NotNil(t, room)
+	assert.NotNil(t, participants)
+	assert.Len(t, *participants, 1)
+	assert.Equal(t, ATTENDEE, (*participants)[0].Role)
 }
 
 func TestUpdateParticipants(t *testing.T) {
-	client := New(host, key)
-	room, err := client.UpdateParticipants(
+	client, srv := newTestRooms(t)
+	id := srv.CreateIdentity()
+	room := createTestRoom(t, client, CreateRoomParticipant(id, PRESENTER))
+	participants, err := client.UpdateParticipants(
 		context.TODO(),
-		roomid,
+		room.Id,
 		CreateRoomParticipant(id, ATTENDEE),
 	)
 	assert.Nil(t, err)
-	assert.NotNil(t, room)
+	assert.NotNil(t, participants)
+	assert.Equal(t, ATTENDEE, (*participants)[0].Role)
 }
 
 func TestRemoveParticipant(t *testing.T) {
-	client := New(host, key)
-	room, err := client.RemoveParticipants(
+	client, srv := newTestRooms(t)
+	id := srv.CreateIdentity()
+	room := createTestRoom(t, client, CreateRoomParticipant(id, PRESENTER))
+	participants, err := client.RemoveParticipants(
 		context.TODO(),
-		roomid,
+		room.Id,
 		RemoveRoomParticipant(id),
 	)
 	assert.Nil(t, err)
+	assert.NotNil(t, participants)
+	assert.Empty(t, *participants)
+}
+
+func TestGetRoomWithEntraCredential(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token_type":"Bearer","expires_in":3600,"access_token":"aad-token"}`))
+	}))
+	defer tokenSrv.Close()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer aad-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/rooms/room", r.URL.Path)
+		w.Write([]byte(`{"id":"room","roomJoinPolicy":"InviteOnly"}`))
+	}))
+	defer srv.Close()
+	client := NewWithCredential(
+		strings.TrimPrefix(srv.URL, "https://"),
+		acsclient.NewClientSecretCredential("tenant", "app", "secret", &acsclient.ClientSecretCredentialOptions{
+			TokenEndpoint: tokenSrv.URL,
+		}),
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	room, err := client.GetRoom(context.TODO(), "room")
+	assert.Nil(t, err)
+	assert.Equal(t, "room", room.Id)
+	assert.Equal(t, INVITE_ONLY, room.RoomJoinPolicy)
+}
+
+func TestRoomsOverPlainHTTPWithBasePath(t *testing.T) {
+	srv := acstest.NewServerWithOptions(&acstest.ServerOptions{
+		PlainHTTP: true,
+		BasePath:  "/acs",
+	})
+	defer srv.Close()
+	client, err := NewFromConnectionString(srv.ConnectionString(), srv.ClientOptions()...)
+	assert.Nil(t, err)
+	room := createTestRoom(t, client)
+	got, err := client.GetRoom(context.TODO(), room.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, room.Id, got.Id)
+}
+
+func TestRoomIdIsEscaped(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rooms/a%2Fb%3Fc", r.URL.EscapedPath())
+		w.Write([]byte(`{"id":"a/b?c"}`))
+	}))
+	defer srv.Close()
+	client := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"c2VjcmV0",
+		acsclient.WithHTTPClient(srv.Client()),
+	)
+	room, err := client.GetRoom(context.TODO(), "a/b?c")
+	assert.Nil(t, err)
+	assert.Equal(t, "a/b?c", room.Id)
+}
+
+func TestCreateRoomIsRepeatable(t *testing.T) {
+	client, srv := newTestRooms(t)
+	ctx := acsclient.WithRepeatabilityRequestID(context.TODO(), acsclient.NewRepeatabilityRequestID())
+	opts := &CreateRoomOptions{
+		ValidFrom:      time.Now(),
+		ValidUntil:     time.Now().Add(time.Hour),
+		RoomJoinPolicy: INVITE_ONLY,
+	}
+	first, err := client.CreateRoom(ctx, opts)
+	assert.Nil(t, err)
+	second, err := client.CreateRoom(ctx, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Id, second.Id)
+	assert.Len(t, srv.Rooms(), 1)
+}
+
+func newGATestRooms(t *testing.T) (Rooms, *acstest.Server) {
+	client, srv := newTestRooms(t)
+	return client.WithAPIVersion(APIVersion2024_04_15), srv
+}
+
+func TestGACreateRoom(t *testing.T) {
+	client, srv := newGATestRooms(t)
+	id := srv.CreateIdentity()
+	room, err := client.CreateRoom(
+		context.TODO(),
+		&CreateRoomOptions{
+			ValidFrom:          time.Now(),
+			ValidUntil:         time.Now().Add(time.Hour),
+			PstnDialOutEnabled: true,
+			Participants: []RoomParticipant{
+				CreateRoomParticipant(id, PRESENTER),
+			},
+		},
+	)
+	assert.Nil(t, err)
+	assert.True(t, room.PstnDialOutEnabled)
+	assert.False(t, room.CreatedAt.IsZero())
+	assert.Equal(t, room.CreatedAt, room.CreatedDateTime)
+	got, err := client.GetRoom(context.TODO(), room.Id)
+	assert.Nil(t, err)
+	assert.True(t, got.PstnDialOutEnabled)
+	participants, err := client.GetParticipants(context.TODO(), room.Id)
+	assert.Nil(t, err)
+	assert.Len(t, *participants, 1)
+	assert.Equal(t, id, (*participants)[0].CommunicationIdentifier.RawID())
+	assert.Equal(t, PRESENTER, (*participants)[0].Role)
+}
+
+func TestGAUpdateRoom(t *testing.T) {
+	client, _ := newGATestRooms(t)
+	created := createTestRoom(t, client)
+	validUntil := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	enabled := true
+	room, err := client.UpdateRoom(
+		context.TODO(),
+		created.Id,
+		&UpdateRoomOptions{
+			ValidFrom:          created.ValidFrom,
+			ValidUntil:         validUntil,
+			PstnDialOutEnabled: &enabled,
+		},
+	)
+	assert.Nil(t, err)
+	assert.True(t, validUntil.Equal(room.ValidUntil))
+	assert.True(t, room.PstnDialOutEnabled)
+}
+
+func TestGAUpdateRoomKeepsUnsetFields(t *testing.T) {
+	client, _ := newGATestRooms(t)
+	created, err := client.CreateRoom(context.TODO(), &CreateRoomOptions{
+		ValidFrom:          time.Now(),
+		ValidUntil:         time.Now().Add(time.Hour),
+		PstnDialOutEnabled: true,
+	})
+	assert.Nil(t, err)
+	validUntil := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	room, err := client.UpdateRoom(
+		context.TODO(),
+		created.Id,
+		&UpdateRoomOptions{
+			ValidUntil: validUntil,
+		},
+	)
+	assert.Nil(t, err)
+	assert.True(t, validUntil.Equal(room.ValidUntil))
+	assert.True(t, created.ValidFrom.Equal(room.ValidFrom))
+	assert.True(t, room.PstnDialOutEnabled)
+	got, err := client.GetRoom(context.TODO(), created.Id)
+	assert.Nil(t, err)
+	assert.True(t, got.PstnDialOutEnabled)
+}
+
+func TestGAUpdateRoomSendsOnlySetFields(t *testing.T) {
+	validUntil := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"validUntil":"`+validUntil.Format(time.RFC3339)+`"}`, string(body))
+		w.Write([]byte(`{"id":"room","pstnDialOutEnabled":true}`))
+	}))
+	defer srv.Close()
+	client := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"c2VjcmV0",
+		acsclient.WithHTTPClient(srv.Client()),
+	).WithAPIVersion(APIVersion2024_04_15)
+	room, err := client.UpdateRoom(context.TODO(), "room", &UpdateRoomOptions{
+		ValidUntil: validUntil,
+	})
+	assert.Nil(t, err)
+	assert.True(t, room.PstnDialOutEnabled)
+}
+
+func TestGAUpdateRoomReturnsRoomWhenUpsertFails(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rooms/room/participants" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":"BadRequest","message":"invalid participant"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"room","pstnDialOutEnabled":true}`))
+	}))
+	defer srv.Close()
+	client := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"c2VjcmV0",
+		acsclient.WithHTTPClient(srv.Client()),
+	).WithAPIVersion(APIVersion2024_04_15)
+	room, err := client.UpdateRoom(context.TODO(), "room", &UpdateRoomOptions{
+		ValidUntil:   time.Now().Add(time.Hour),
+		Participants: []RoomParticipant{CreateRoomParticipant("8:acs:x", ATTENDEE)},
+	})
+	assert.True(t, acsclient.HasErrorCode(err, "BadRequest"))
 	assert.NotNil(t, room)
+	assert.Equal(t, "room", room.Id)
+}
+
+func TestGAUpsertAndRemoveParticipants(t *testing.T) {
+	client, srv := newGATestRooms(t)
+	id, id2 := srv.CreateIdentity(), srv.CreateIdentity()
+	room := createTestRoom(t, client)
+	err := client.UpsertParticipants(
+		context.TODO(),
+		room.Id,
+		CreateRoomParticipant(id, PRESENTER),
+		CreateRoomParticipant(id2, ATTENDEE),
+	)
+	assert.Nil(t, err)
+	participants, err := client.UpdateParticipants(
+		context.TODO(),
+		room.Id,
+		CreateRoomParticipant(id2, CONSUMER),
+	)
+	assert.Nil(t, err)
+	roles := map[string]Role{}
+	for _, p := range *participants {
+		roles[p.CommunicationIdentifier.RawID()] = p.Role
+	}
+	assert.Equal(t, map[string]Role{id: PRESENTER, id2: CONSUMER}, roles)
+	participants, err = client.RemoveParticipants(
+		context.TODO(),
+		room.Id,
+		RemoveRoomParticipant(id),
+	)
+	assert.Nil(t, err)
+	assert.Len(t, *participants, 1)
+	assert.Equal(t, id2, (*participants)[0].CommunicationIdentifier.RawID())
+}
+
+func TestGARemoveParticipantsSendsNullRole(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"value":[]}`))
+			return
+		}
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/rooms/room/participants", r.URL.Path)
+		assert.Equal(t, "2024-04-15", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"participants":{"8:acs:x":null}}`, string(body))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	client := New(
+		strings.TrimPrefix(srv.URL, "https://"),
+		"c2VjcmV0",
+		acsclient.WithHTTPClient(srv.Client()),
+	).WithAPIVersion(APIVersion2024_04_15)
+	participants, err := client.RemoveParticipants(context.TODO(), "room", RemoveRoomParticipant("8:acs:x"))
+	assert.Nil(t, err)
+	assert.Empty(t, *participants)
+}
+
+func TestUpsertParticipantsRequiresGA(t *testing.T) {
+	client, srv := newTestRooms(t)
+	room := createTestRoom(t, client)
+	err := client.UpsertParticipants(
+		context.TODO(),
+		room.Id,
+		CreateRoomParticipant(srv.CreateIdentity(), ATTENDEE),
+	)
+	assert.Equal(t, ERR_ROOMS_REQUIRES_GA_API_VERSION, err)
 }
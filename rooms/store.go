@@ -0,0 +1,62 @@
+package rooms
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManagedRoom is a room tracked by a Manager.
+type ManagedRoom struct {
+	ID             string
+	ValidFrom      time.Time
+	ValidUntil     time.Time
+	RoomJoinPolicy RoomJoinPolicy
+}
+
+// RoomStore persists the rooms a Manager tracks, so they can be cleaned
+// up after a restart. Implementations must be safe for concurrent use.
+type RoomStore interface {
+	Save(ctx context.Context, room ManagedRoom) error
+	Delete(ctx context.Context, roomId string) error
+	List(ctx context.Context) ([]ManagedRoom, error)
+}
+
+type memoryRoomStore struct {
+	mu    sync.Mutex
+	rooms map[string]ManagedRoom
+}
+
+// NewMemoryRoomStore returns a RoomStore that keeps rooms in memory.
+func NewMemoryRoomStore() RoomStore {
+	return &memoryRoomStore{rooms: map[string]ManagedRoom{}}
+}
+
+func (s *memoryRoomStore) Save(ctx context.Context, room ManagedRoom) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rooms[room.ID] = room
+	return nil
+}
+
+func (s *memoryRoomStore) Delete(ctx context.Context, roomId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rooms, roomId)
+	return nil
+}
+
+// List returns the rooms sorted by id.
+func (s *memoryRoomStore) List(ctx context.Context) ([]ManagedRoom, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rooms := make([]ManagedRoom, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].ID < rooms[j].ID
+	})
+	return rooms, nil
+}
@@ -0,0 +1,243 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/karim-w/go-azure-communication-services/client"
+)
+
+// MaxParticipantsPerRequest is the most participants SyncParticipants
+// sends in a single request.
+const MaxParticipantsPerRequest = 100
+
+// ParticipantFailure is a participant SyncParticipants could not change.
+type ParticipantFailure struct {
+	Participant RoomParticipant
+	Err         error
+}
+
+// SyncReport is what SyncParticipants changed. Updated holds the new
+// roles. A participant that could not be changed is listed in Failed
+// only.
+type SyncReport struct {
+	Added   []RoomParticipant
+	Updated []RoomParticipant
+	Removed []RoomParticipant
+	Failed  []ParticipantFailure
+}
+
+type syncKind int
+
+const (
+	syncAdd syncKind = iota
+	syncUpdate
+	syncRemove
+)
+
+// SyncParticipants makes the room's participants match desired: missing
+// participants are added, roles are changed and everyone else is removed.
+// Changes are sent in batches of MaxParticipantsPerRequest; when the
+// service rejects a batch because of specific participants, the rest of
+// the batch is sent again without them. Throttling, server and
+// authorization errors stop the sync and every change not yet made is
+// reported as failed. An empty role means ATTENDEE.
+//
+// The error is set when desired is invalid, the current participants
+// cannot be read or the sync was stopped; in the last case it is the error
+// that stopped it and the partial report is returned with it. Failures of
+// individual participants that did not stop the sync are only in the
+// report.
+func (c *_RoomsClient) SyncParticipants(
+	ctx context.Context,
+	roomId string,
+	desired []RoomParticipant,
+) (*SyncReport, error) {
+	problems := &ValidationError{}
+	validateParticipants(problems, desired)
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+	current, err := c.GetParticipants(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	roles := map[string]Role{}
+	for _, p := range *current {
+		roles[p.CommunicationIdentifier.RawID()] = roleOrDefault(p.Role)
+	}
+	wanted := map[string]bool{}
+	adds, updates, removes := []RoomParticipant{}, []RoomParticipant{}, []RoomParticipant{}
+	for _, p := range desired {
+		id := p.CommunicationIdentifier.RawID()
+		wanted[id] = true
+		p.Role = roleOrDefault(p.Role)
+		role, ok := roles[id]
+		switch {
+		case !ok:
+			adds = append(adds, p)
+		case role != p.Role:
+			updates = append(updates, p)
+		}
+	}
+	for _, p := range *current {
+		if !wanted[p.CommunicationIdentifier.RawID()] {
+			removes = append(removes, p)
+		}
+	}
+	report := &SyncReport{
+		Added:   []RoomParticipant{},
+		Updated: []RoomParticipant{},
+		Removed: []RoomParticipant{},
+		Failed:  []ParticipantFailure{},
+	}
+	changes := []struct {
+		kind         syncKind
+		participants []RoomParticipant
+		done         *[]RoomParticipant
+	}{
+		{syncRemove, removes, &report.Removed},
+		{syncAdd, adds, &report.Added},
+		{syncUpdate, updates, &report.Updated},
+	}
+	var stop error
+	for _, change := range changes {
+		if stop != nil {
+			report.fail(change.participants, stop)
+			continue
+		}
+		*change.done, stop = c.syncBatches(ctx, roomId, change.kind, change.participants, report)
+	}
+	return report, stop
+}
+
+func (r *SyncReport) fail(participants []RoomParticipant, err error) {
+	for _, p := range participants {
+		r.Failed = append(r.Failed, ParticipantFailure{p, err})
+	}
+}
+
+// syncBatches applies one kind of change in batches and returns the
+// participants it changed, adding the ones it could not to report. When
+// the service blames specific participants for a failed batch they are
+// dropped and the rest of the batch is sent again; any other failure fails
+// the whole batch. Errors that no other request would escape, see
+// stopsSync, fail every participant not yet reported and are returned.
+func (c *_RoomsClient) syncBatches(
+	ctx context.Context,
+	roomId string,
+	kind syncKind,
+	participants []RoomParticipant,
+	report *SyncReport,
+) ([]RoomParticipant, error) {
+	done := []RoomParticipant{}
+	for start := 0; start < len(participants); start += MaxParticipantsPerRequest {
+		end := start + MaxParticipantsPerRequest
+		if end > len(participants) {
+			end = len(participants)
+		}
+		batch := participants[start:end]
+		for len(batch) > 0 {
+			err := ctx.Err()
+			if err == nil {
+				err = c.applySync(ctx, roomId, kind, batch)
+			}
+			if err == nil {
+				done = append(done, batch...)
+				break
+			}
+			if stopsSync(ctx, err) {
+				report.fail(batch, err)
+				report.fail(participants[end:], err)
+				return done, err
+			}
+			blamed, rest := blamedParticipants(err, batch)
+			if len(blamed) == 0 {
+				report.fail(batch, err)
+				break
+			}
+			report.fail(blamed, err)
+			batch = rest
+		}
+	}
+	return done, nil
+}
+
+// stopsSync reports whether err means further requests would fail too:
+// a canceled context, a failure to reach the service, an authorization
+// failure, a missing room, throttling or a server error.
+func stopsSync(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	var respErr *client.ResponseError
+	if !errors.As(err, &respErr) {
+		return true
+	}
+	switch respErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests:
+		return true
+	}
+	return respErr.StatusCode >= 500
+}
+
+// blamedParticipants splits batch into the participants the error targets,
+// by raw id in its target or details, and the rest.
+func blamedParticipants(
+	err error,
+	batch []RoomParticipant,
+) ([]RoomParticipant, []RoomParticipant) {
+	var respErr *client.ResponseError
+	if !errors.As(err, &respErr) {
+		return nil, batch
+	}
+	targets := map[string]bool{}
+	if respErr.Target != "" {
+		targets[respErr.Target] = true
+	}
+	for _, detail := range respErr.Details {
+		if detail.Target != "" {
+			targets[detail.Target] = true
+		}
+	}
+	blamed, rest := []RoomParticipant{}, []RoomParticipant{}
+	for _, p := range batch {
+		if targets[p.CommunicationIdentifier.RawID()] {
+			blamed = append(blamed, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+	return blamed, rest
+}
+
+// applySync sends one batch: a merge-patch on a GA API version, otherwise
+// the preview participants:add, :update or :remove call.
+func (c *_RoomsClient) applySync(
+	ctx context.Context,
+	roomId string,
+	kind syncKind,
+	batch []RoomParticipant,
+) error {
+	if c.version.isGA() {
+		return c.patchParticipants(ctx, roomId, participantsPatch(batch, kind == syncRemove))
+	}
+	var err error
+	switch kind {
+	case syncAdd:
+		_, err = c.AddParticipants(ctx, roomId, batch...)
+	case syncUpdate:
+		_, err = c.UpdateParticipants(ctx, roomId, batch...)
+	case syncRemove:
+		_, err = c.RemoveParticipants(ctx, roomId, batch...)
+	}
+	return err
+}
+
+func roleOrDefault(role Role) Role {
+	if role == "" {
+		return ATTENDEE
+	}
+	return role
+}
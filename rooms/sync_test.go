@@ -0,0 +1,249 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/karim-w/go-azure-communication-services/acstest"
+	acsclient "github.com/karim-w/go-azure-communication-services/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func rawIDs(participants []RoomParticipant) []string {
+	ids := []string{}
+	for _, p := range participants {
+		ids = append(ids, p.CommunicationIdentifier.RawID())
+	}
+	return ids
+}
+
+func participantRoles(t *testing.T, client Rooms, roomId string) map[string]Role {
+	participants, err := client.GetParticipants(context.TODO(), roomId)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	roles := map[string]Role{}
+	for _, p := range *participants {
+		roles[p.CommunicationIdentifier.RawID()] = p.Role
+	}
+	return roles
+}
+
+func testSyncParticipants(t *testing.T, client Rooms, srv *acstest.Server) {
+	a, b, c, d := srv.CreateIdentity(), srv.CreateIdentity(), srv.CreateIdentity(), srv.CreateIdentity()
+	room := createTestRoom(t, client,
+		CreateRoomParticipant(a, PRESENTER),
+		CreateRoomParticipant(b, ATTENDEE),
+		CreateRoomParticipant(c, ATTENDEE),
+	)
+	report, err := client.SyncParticipants(context.TODO(), room.Id, []RoomParticipant{
+		CreateRoomParticipant(a, ATTENDEE),
+		CreateRoomParticipant(b, ""),
+		CreateRoomParticipant(d, PRESENTER),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{d}, rawIDs(report.Added))
+	assert.Equal(t, []string{a}, rawIDs(report.Updated))
+	assert.Equal(t, ATTENDEE, report.Updated[0].Role)
+	assert.Equal(t, []string{c}, rawIDs(report.Removed))
+	assert.Empty(t, report.Failed)
+	assert.Equal(t, map[string]Role{a: ATTENDEE, b: ATTENDEE, d: PRESENTER}, participantRoles(t, client, room.Id))
+
+	report, err = client.SyncParticipants(context.TODO(), room.Id, []RoomParticipant{
+		CreateRoomParticipant(a, ATTENDEE),
+		CreateRoomParticipant(b, ATTENDEE),
+		CreateRoomParticipant(d, PRESENTER),
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Added)
+	assert.Empty(t, report.Updated)
+	assert.Empty(t, report.Removed)
+}
+
+func TestSyncParticipants(t *testing.T) {
+	client, srv := newTestRooms(t)
+	testSyncParticipants(t, client, srv)
+}
+
+func TestSyncParticipantsGA(t *testing.T) {
+	client, srv := newGATestRooms(t)
+	testSyncParticipants(t, client, srv)
+}
+
+func TestSyncParticipantsBatches(t *testing.T) {
+	srv := acstest.NewServer()
+	t.Cleanup(srv.Close)
+	var patches int32
+	client := New(srv.Host(), srv.Key(), append(srv.ClientOptions(), acsclient.WithPerCallPolicy(
+		func(req *http.Request, next acsclient.Next) (*http.Response, error) {
+			if req.Method == http.MethodPatch {
+				atomic.AddInt32(&patches, 1)
+			}
+			return next(req)
+		},
+	))...).WithAPIVersion(APIVersion2024_04_15)
+	room := createTestRoom(t, client)
+	desired := []RoomParticipant{}
+	for i := 0; i < MaxParticipantsPerRequest+20; i++ {
+		desired = append(desired, CreateRoomParticipant(fmt.Sprintf("8:acs:%d", i), ATTENDEE))
+	}
+	report, err := client.SyncParticipants(context.TODO(), room.Id, desired)
+	assert.Nil(t, err)
+	assert.Len(t, report.Added, len(desired))
+	assert.Equal(t, int32(2), patches)
+	assert.Len(t, participantRoles(t, client, room.Id), len(desired))
+}
+
+// newRejectingRooms returns a GA client whose participant patches are
+// answered by reject when it returns a status, and counts those patches.
+func newRejectingRooms(
+	t *testing.T,
+	reject func(body string) (int, string),
+) (Rooms, *int32) {
+	srv := acstest.NewServer()
+	t.Cleanup(srv.Close)
+	var patches int32
+	client := New(srv.Host(), srv.Key(), append(srv.ClientOptions(), acsclient.WithPerCallPolicy(
+		func(req *http.Request, next acsclient.Next) (*http.Response, error) {
+			if req.Method != http.MethodPatch || !strings.HasSuffix(req.URL.Path, "/participants") {
+				return next(req)
+			}
+			atomic.AddInt32(&patches, 1)
+			body, _ := req.GetBody()
+			b, _ := io.ReadAll(body)
+			if status, envelope := reject(string(b)); status != 0 {
+				return &http.Response{
+					StatusCode: status,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader(envelope)),
+					Request:    req,
+				}, nil
+			}
+			return next(req)
+		},
+	))...).WithAPIVersion(APIVersion2024_04_15)
+	return client, &patches
+}
+
+func TestSyncParticipantsDropsBlamedParticipants(t *testing.T) {
+	client, patches := newRejectingRooms(t, func(body string) (int, string) {
+		if strings.Contains(body, "8:acs:bad") {
+			return http.StatusBadRequest, `{"error":{"code":"InvalidIdentifier","message":"unknown user","target":"8:acs:bad"}}`
+		}
+		return 0, ""
+	})
+	room := createTestRoom(t, client)
+	report, err := client.SyncParticipants(context.TODO(), room.Id, []RoomParticipant{
+		CreateRoomParticipant("8:acs:good", ATTENDEE),
+		CreateRoomParticipant("8:acs:bad", ATTENDEE),
+		CreateRoomParticipant("8:acs:other", PRESENTER),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"8:acs:good", "8:acs:other"}, rawIDs(report.Added))
+	assert.Len(t, report.Failed, 1)
+	assert.Equal(t, "8:acs:bad", report.Failed[0].Participant.CommunicationIdentifier.RawID())
+	assert.True(t, acsclient.HasErrorCode(report.Failed[0].Err, "InvalidIdentifier"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(patches))
+	assert.Equal(t, map[string]Role{"8:acs:good": ATTENDEE, "8:acs:other": PRESENTER}, participantRoles(t, client, room.Id))
+}
+
+func TestSyncParticipantsFailsUnattributedBatch(t *testing.T) {
+	client, patches := newRejectingRooms(t, func(body string) (int, string) {
+		return http.StatusBadRequest, `{"error":{"code":"BadRequest","message":"rejected"}}`
+	})
+	room := createTestRoom(t, client)
+	report, err := client.SyncParticipants(context.TODO(), room.Id, []RoomParticipant{
+		CreateRoomParticipant("8:acs:a", ATTENDEE),
+		CreateRoomParticipant("8:acs:b", ATTENDEE),
+		CreateRoomParticipant("8:acs:c", ATTENDEE),
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Added)
+	assert.Len(t, report.Failed, 3)
+	assert.Equal(t, int32(1), atomic.LoadInt32(patches))
+}
+
+func TestSyncParticipantsStopsOnThrottling(t *testing.T) {
+	client, patches := newRejectingRooms(t, func(body string) (int, string) {
+		return http.StatusTooManyRequests, ""
+	})
+	room := createTestRoom(t, client, CreateRoomParticipant("8:acs:old", ATTENDEE))
+	desired := []RoomParticipant{}
+	for i := 0; i < 2*MaxParticipantsPerRequest; i++ {
+		desired = append(desired, CreateRoomParticipant(fmt.Sprintf("8:acs:%d", i), ATTENDEE))
+	}
+	report, err := client.SyncParticipants(context.TODO(), room.Id, desired)
+	assert.True(t, acsclient.HasErrorCode(err, "TooManyRequests"))
+	assert.Empty(t, report.Removed)
+	assert.Empty(t, report.Added)
+	assert.Len(t, report.Failed, len(desired)+1)
+	for _, f := range report.Failed {
+		assert.True(t, acsclient.HasErrorCode(f.Err, "TooManyRequests"))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(patches))
+}
+
+func TestSyncParticipantsReportsEachParticipantOnceWhenStopped(t *testing.T) {
+	client, patches := newRejectingRooms(t, func(body string) (int, string) {
+		if strings.Contains(body, "8:acs:bad") {
+			return http.StatusBadRequest, `{"error":{"code":"InvalidIdentifier","message":"unknown user","target":"8:acs:bad"}}`
+		}
+		return http.StatusTooManyRequests, ""
+	})
+	room := createTestRoom(t, client)
+	desired := []RoomParticipant{CreateRoomParticipant("8:acs:bad", ATTENDEE)}
+	for i := 0; i < MaxParticipantsPerRequest; i++ {
+		desired = append(desired, CreateRoomParticipant(fmt.Sprintf("8:acs:%d", i), ATTENDEE))
+	}
+	report, err := client.SyncParticipants(context.TODO(), room.Id, desired)
+	assert.True(t, acsclient.HasErrorCode(err, "TooManyRequests"))
+	assert.Empty(t, report.Added)
+	assert.Len(t, report.Failed, len(desired))
+	seen := map[string]bool{}
+	for _, f := range report.Failed {
+		id := f.Participant.CommunicationIdentifier.RawID()
+		assert.False(t, seen[id], "%s reported twice", id)
+		seen[id] = true
+		if id == "8:acs:bad" {
+			assert.True(t, acsclient.HasErrorCode(f.Err, "InvalidIdentifier"))
+		} else {
+			assert.True(t, acsclient.HasErrorCode(f.Err, "TooManyRequests"))
+		}
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(patches))
+}
+
+func TestSyncParticipantsStopsOnCanceledContext(t *testing.T) {
+	client, patches := newRejectingRooms(t, func(body string) (int, string) {
+		return 0, ""
+	})
+	room := createTestRoom(t, client)
+	ctx, cancel := context.WithCancel(context.TODO())
+	report := &SyncReport{}
+	cancel()
+	done, err := client.(*_RoomsClient).syncBatches(ctx, room.Id, syncAdd, []RoomParticipant{
+		CreateRoomParticipant("8:acs:a", ATTENDEE),
+	}, report)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, done)
+	assert.Len(t, report.Failed, 1)
+	assert.Equal(t, int32(0), atomic.LoadInt32(patches))
+}
+
+func TestSyncParticipantsRejectsInvalidDesiredState(t *testing.T) {
+	client, _ := newTestRooms(t)
+	room := createTestRoom(t, client)
+	_, err := client.SyncParticipants(context.TODO(), room.Id, []RoomParticipant{
+		CreateRoomParticipant("8:acs:a", ATTENDEE),
+		CreateRoomParticipant("8:acs:a", PRESENTER),
+	})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "participants[1]", validationErr.Problems[0].Field)
+}
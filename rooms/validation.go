@@ -0,0 +1,137 @@
+package rooms
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxRoomValidity is the longest a room can be valid for.
+	MaxRoomValidity = 180 * 24 * time.Hour
+	// ValidFromTolerance is how far in the past ValidFrom may be when a
+	// room is created, to allow for clock skew.
+	ValidFromTolerance = 5 * time.Minute
+	// MaxRoomParticipants is the most participants a room can hold.
+	MaxRoomParticipants = 250
+)
+
+// FieldError is one problem with a field of room options.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every problem found in CreateRoomOptions or
+// UpdateRoomOptions. It matches ERR_ROOMS_CREATE_ROOM_INVALID_OPTIONS with
+// errors.Is.
+type ValidationError struct {
+	Problems []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = p.String()
+	}
+	return ERR_ROOMS_CREATE_ROOM_INVALID_OPTIONS.Error() + ": " + strings.Join(problems, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ERR_ROOMS_CREATE_ROOM_INVALID_OPTIONS
+}
+
+func (e *ValidationError) add(field string, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, FieldError{field, fmt.Sprintf(format, args...)})
+}
+
+func (r Role) isKnown() bool {
+	switch r {
+	case PRESENTER, ATTENDEE, CONSUMER, COLLABORATOR:
+		return true
+	}
+	return false
+}
+
+func (p RoomJoinPolicy) isKnown() bool {
+	return p == INVITE_ONLY || p == COMMUNICATION_SERVICE_USER
+}
+
+// validate checks room options for version at now. Creating a room with
+// the preview API needs both times and a join policy; the GA API defaults
+// missing times and has no join policy. An update only checks the fields
+// it sets. ValidFrom may only be in the past when updating, since an
+// existing room keeps its start. An update without ValidFrom keeps the
+// room's unknown start, so ValidUntil is then not checked against it.
+func (c *CreateRoomOptions) validate(
+	version APIVersion,
+	now time.Time,
+	create bool,
+) error {
+	problems := &ValidationError{}
+	if create && !version.isGA() {
+		if c.ValidFrom.IsZero() {
+			problems.add("validFrom", "is required")
+		}
+		if c.ValidUntil.IsZero() {
+			problems.add("validUntil", "is required")
+		}
+		if c.RoomJoinPolicy == "" {
+			problems.add("roomJoinPolicy", "is required")
+		}
+	}
+	if c.RoomJoinPolicy != "" && !c.RoomJoinPolicy.isKnown() {
+		problems.add("roomJoinPolicy", "unknown policy %q", c.RoomJoinPolicy)
+	}
+	from, until := c.ValidFrom, c.ValidUntil
+	if from.IsZero() {
+		from = now
+	}
+	if create && !c.ValidFrom.IsZero() && c.ValidFrom.Before(now.Add(-ValidFromTolerance)) {
+		problems.add("validFrom", "is in the past")
+	}
+	if !c.ValidUntil.IsZero() && (create || !c.ValidFrom.IsZero()) {
+		if !until.After(from) {
+			problems.add("validUntil", "must be after validFrom")
+		} else if until.Sub(from) > MaxRoomValidity {
+			problems.add("validUntil", "must be within %d days of validFrom", MaxRoomValidity/(24*time.Hour))
+		}
+	}
+	validateParticipants(problems, c.Participants)
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// validateParticipants checks the participant count, identifiers, roles
+// and duplicates.
+func validateParticipants(
+	problems *ValidationError,
+	participants []RoomParticipant,
+) {
+	if len(participants) > MaxRoomParticipants {
+		problems.add("participants", "at most %d participants are allowed, got %d", MaxRoomParticipants, len(participants))
+	}
+	seen := map[string]int{}
+	for i, p := range participants {
+		field := fmt.Sprintf("participants[%d]", i)
+		id := p.CommunicationIdentifier.RawID()
+		if id == "" {
+			problems.add(field, "communication identifier is required")
+			continue
+		}
+		if p.Role != "" && !p.Role.isKnown() {
+			problems.add(field, "unknown role %q", p.Role)
+		}
+		if first, ok := seen[id]; ok {
+			problems.add(field, "duplicates participants[%d] (%s)", first, id)
+			continue
+		}
+		seen[id] = i
+	}
+}
@@ -0,0 +1,99 @@
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	now := time.Now()
+	opts := &CreateRoomOptions{
+		ValidFrom:      now.Add(-time.Hour),
+		ValidUntil:     now.Add(200 * 24 * time.Hour),
+		RoomJoinPolicy: "Anyone",
+		Participants: []RoomParticipant{
+			CreateRoomParticipant("8:acs:a", PRESENTER),
+			CreateRoomParticipant("8:acs:b", "Host"),
+			CreateRoomParticipant("8:acs:a", ATTENDEE),
+		},
+	}
+	err := opts.validate(defaultAPIVersion, now, true)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.True(t, errors.Is(err, ERR_ROOMS_CREATE_ROOM_INVALID_OPTIONS))
+	assert.Equal(t, []FieldError{
+		{"roomJoinPolicy", `unknown policy "Anyone"`},
+		{"validFrom", "is in the past"},
+		{"validUntil", "must be within 180 days of validFrom"},
+		{"participants[1]", `unknown role "Host"`},
+		{"participants[2]", "duplicates participants[0] (8:acs:a)"},
+	}, validationErr.Problems)
+	assert.Contains(t, err.Error(), "validFrom: is in the past")
+}
+
+func TestValidatePreviewRequiresTimesAndPolicy(t *testing.T) {
+	err := (&CreateRoomOptions{}).validate(defaultAPIVersion, time.Now(), true)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Len(t, validationErr.Problems, 3)
+	assert.Nil(t, (&CreateRoomOptions{}).validate(APIVersion2024_04_15, time.Now(), true))
+}
+
+func TestValidatePreviewUpdateChecksOnlySetFields(t *testing.T) {
+	now := time.Now()
+	assert.Nil(t, (&CreateRoomOptions{}).validate(defaultAPIVersion, now, false))
+	assert.Nil(t, (&CreateRoomOptions{RoomJoinPolicy: INVITE_ONLY}).validate(defaultAPIVersion, now, false))
+	err := (&CreateRoomOptions{RoomJoinPolicy: "Anyone"}).validate(defaultAPIVersion, now, false)
+	var problems *ValidationError
+	assert.True(t, errors.As(err, &problems))
+	assert.Equal(t, []FieldError{{"roomJoinPolicy", `unknown policy "Anyone"`}}, problems.Problems)
+}
+
+func TestValidateToleratesSkewAndPastStartOnUpdate(t *testing.T) {
+	now := time.Now()
+	opts := &CreateRoomOptions{
+		ValidFrom:      now.Add(-time.Minute),
+		ValidUntil:     now.Add(time.Hour),
+		RoomJoinPolicy: INVITE_ONLY,
+	}
+	assert.Nil(t, opts.validate(defaultAPIVersion, now, true))
+	opts.ValidFrom = now.Add(-24 * time.Hour)
+	assert.NotNil(t, opts.validate(defaultAPIVersion, now, true))
+	assert.Nil(t, opts.validate(defaultAPIVersion, now, false))
+}
+
+func TestValidateParticipantLimit(t *testing.T) {
+	opts := &CreateRoomOptions{}
+	for i := 0; i <= MaxRoomParticipants; i++ {
+		opts.Participants = append(opts.Participants, CreateRoomParticipant(fmt.Sprintf("8:acs:%d", i), ATTENDEE))
+	}
+	err := opts.validate(APIVersion2024_04_15, time.Now(), true)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "participants", validationErr.Problems[0].Field)
+}
+
+func TestCreateRoomValidatesOptions(t *testing.T) {
+	client, srv := newTestRooms(t)
+	_, err := client.CreateRoom(context.TODO(), &CreateRoomOptions{
+		ValidFrom:      time.Now(),
+		ValidUntil:     time.Now().Add(-time.Hour),
+		RoomJoinPolicy: INVITE_ONLY,
+	})
+	assert.True(t, errors.Is(err, ERR_ROOMS_CREATE_ROOM_INVALID_OPTIONS))
+	assert.Empty(t, srv.Rooms())
+}
+
+func TestValidateUpdateWithoutValidFromSkipsWindow(t *testing.T) {
+	now := time.Now()
+	opts := &CreateRoomOptions{ValidUntil: now.Add(200 * 24 * time.Hour)}
+	assert.Nil(t, opts.validate(APIVersion2024_04_15, now, false))
+	assert.NotNil(t, opts.validate(APIVersion2024_04_15, now, true))
+	opts.ValidFrom = now
+	assert.NotNil(t, opts.validate(APIVersion2024_04_15, now, false))
+}